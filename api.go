@@ -1,45 +1,82 @@
 package vkapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
-	APIEndpoint = "https://api.vk.com/method/%s"
-	APIVersion  = "5.103"
+	APIEndpoint    = "https://api.vk.com/method/%s"
+	APIVersion     = "5.103"
+	DefaultBaseURL = "https://api.vk.com/method/"
 )
 
 type Params map[string]interface{}
 
 type VkAPI struct {
-	Token       string
+	// Token is used when TokenSource is nil.
+	Token string
+	// TokenSource, if set, is asked for a token before every request.
+	// Use it to rotate tokens of a running client.
+	TokenSource TokenSource
 	Client      *http.Client
-	groupLPSubs GroupLPSubs
-	msgLPSubs   MsgLPSubs
+	// BaseURL is the prefix of method URLs, DefaultBaseURL if empty.
+	// Its scheme is also used for long poll servers given without one.
+	BaseURL string
+	// Version is the API version, APIVersion if empty.
+	Version string
+	// DefaultParams are added to every request that doesn't set them,
+	// e.g. lang or test_mode.
+	DefaultParams url.Values
+	// Limiter, if set, throttles every request made by MakeRequest,
+	// e.g. NewUserLimiter or NewGroupLimiter for the token type.
+	Limiter RateLimiter
+	// Retry is consulted when a request fails. Nil disables retries.
+	Retry *RetryPolicy
+	// Batcher, if set, merges concurrent requests into execute calls.
+	Batcher *Batcher
+	// CaptchaHandler and ValidationHandler, if set, answer challenges
+	// returned by the API and the failed request is sent again.
+	CaptchaHandler    CaptchaHandler
+	ValidationHandler ValidationHandler
+	// Logger receives diagnostics. Nothing is logged if it is nil.
+	Logger Logger
+	// Cache, if set, serves repeated calls of read-only methods.
+	// It is bypassed when TokenSource may change the token.
+	Cache *ResponseCache
+	// EventErrorHandler, if set, receives long poll events that can't
+	// be decoded and panics recovered from their handlers. Both are
+	// logged anyway.
+	EventErrorHandler func(ctx context.Context, err error)
+	// Dispatcher, if set, runs long poll event handlers concurrently.
+	// They run in the polling goroutine otherwise.
+	Dispatcher *Dispatcher
+
+	mu           sync.RWMutex
+	interceptors []Interceptor
+
+	groupEvents registry[string, *GroupLPUpdates]
+	msgEvents   registry[int, interface{}]
 }
 
 type APIResponse struct {
 	Response      json.RawMessage `json:"response"`
 	ResponseError json.RawMessage `json:"error"`
+	ExecuteErrors json.RawMessage `json:"execute_errors"`
 }
 
-type APIError struct {
-	Code int    `json:"error_code"`
-	Msg  string `json:"error_msg"`
-	Raw  json.RawMessage
-}
-
+// Method is an API request. Its params are taken from ValuesEncoder
+// or Valuer if it implements one, otherwise its `vk` tagged fields are
+// encoded with EncodeValues. Encoding errors are returned by Call.
 type Method interface {
 	Name() string
-	Values() url.Values
-}
-
-func (e APIError) Error() string {
-	return e.Msg
 }
 
 func NewVkAPI(token string) *VkAPI {
@@ -50,34 +87,185 @@ func NewVkAPIWithClient(token string, client *http.Client) *VkAPI {
 	return &VkAPI{
 		Token:  token,
 		Client: client,
-		groupLPSubs: GroupLPSubs{
-			events: make(map[string]func(m *GroupLPUpdates)),
-		},
-		msgLPSubs: MsgLPSubs{
-			events: make(map[int]func(m interface{})),
-		},
 	}
 }
 
+func (vk *VkAPI) baseURL() string {
+	if vk.BaseURL == "" {
+		return DefaultBaseURL
+	}
+	if !strings.HasSuffix(vk.BaseURL, "/") {
+		return vk.BaseURL + "/"
+	}
+	return vk.BaseURL
+}
+
+func (vk *VkAPI) version() string {
+	if vk.Version == "" {
+		return APIVersion
+	}
+	return vk.Version
+}
+
+// lpURL returns the a_check URL of a long poll server. Servers given
+// without a scheme get the one of BaseURL.
+func (vk *VkAPI) lpURL(server string, query url.Values) string {
+	if !strings.Contains(server, "://") {
+		scheme := "https"
+		if u, err := url.Parse(vk.baseURL()); err == nil && u.Scheme != "" {
+			scheme = u.Scheme
+		}
+		server = scheme + "://" + server
+	}
+	return server + "?" + query.Encode()
+}
+
 func (vk *VkAPI) MakeRequest(method string, params url.Values) (*APIResponse, error) {
+	return vk.MakeRequestContext(context.Background(), method, params)
+}
+
+// MakeRequestContext calls the API method with the given params.
+// If ctx is cancelled or its deadline is exceeded, ctx.Err() is returned as is.
+func (vk *VkAPI) MakeRequestContext(ctx context.Context, method string, params url.Values) (*APIResponse, error) {
 	if params == nil {
 		params = url.Values{}
 	}
-	params.Set("v", APIVersion)
-	params.Set("access_token", vk.Token)
 
-	resp, err := vk.Client.PostForm(fmt.Sprintf(APIEndpoint, method), params)
+	if vk.Cache == nil {
+		return vk.request(ctx, method, params)
+	}
+	token, ok := vk.cacheToken()
+	if !ok {
+		resp, err := vk.request(ctx, method, params)
+		vk.Cache.invalidate(method)
+		return resp, err
+	}
+
+	key := cacheKey(method, params, token)
+	if resp, ok := vk.Cache.get(method, key); ok {
+		return resp, nil
+	}
+	gen := vk.Cache.generation(method)
+	resp, err := vk.request(ctx, method, params)
+	vk.Cache.invalidate(method)
+	if err != nil {
+		return nil, err
+	}
+	vk.Cache.setKey(method, key, gen, resp)
+	return resp, nil
+}
+
+// cacheToken returns the token requests will be sent with if it is
+// known in advance.
+func (vk *VkAPI) cacheToken() (string, bool) {
+	switch s := vk.TokenSource.(type) {
+	case nil:
+		return vk.Token, true
+	case staticTokenSource:
+		return string(s), true
+	}
+	return "", false
+}
+
+func (vk *VkAPI) request(ctx context.Context, method string, params url.Values) (*APIResponse, error) {
+	var (
+		resp *APIResponse
+		err  error
+	)
+	if vk.Batcher != nil && batchable(method) {
+		resp, err = vk.Batcher.enqueue(ctx, method, params)
+	} else {
+		resp, err = vk.send(ctx, method, params, true)
+	}
+	if err != nil {
+		return vk.challenge(ctx, method, params, true, err)
+	}
+	return resp, nil
+}
+
+func (vk *VkAPI) send(ctx context.Context, method string, params url.Values, retry bool) (*APIResponse, error) {
+	for k, v := range vk.DefaultParams {
+		if _, ok := params[k]; !ok {
+			params[k] = v
+		}
+	}
+	if params.Get("v") == "" {
+		params.Set("v", vk.version())
+	}
+
+	if !retry || vk.Retry == nil {
+		return vk.doRequest(ctx, method, params)
+	}
+	return vk.Retry.do(ctx, method, params, vk.doRequest)
+}
+
+func (vk *VkAPI) doRequest(ctx context.Context, method string, params url.Values) (*APIResponse, error) {
+	if vk.Limiter != nil {
+		if err := vk.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	token, err := vk.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := vk.invoker()(ctx, &Invocation{
+		Method: method,
+		Params: params,
+		token:  token,
+	})
+	vk.reportToken(token, err)
+	if err != nil {
+		vk.logger().Debug("api request failed", "method", method, "error", err)
+	}
+	return resp, err
+}
+
+// transport is the innermost Invoker. It adds the access token,
+// sends the request and decodes the response.
+func (vk *VkAPI) transport(ctx context.Context, inv *Invocation) (*APIResponse, error) {
+	form := make(url.Values, len(inv.Params)+1)
+	for k, v := range inv.Params {
+		form[k] = v
+	}
+	form.Set("access_token", inv.token)
+
+	start := time.Now()
+	defer func() {
+		inv.Latency = time.Since(start)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		vk.baseURL()+inv.Method, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, &transportError{op: "request", err: err}
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := vk.Client.Do(req)
 	if resp != nil {
 		defer resp.Body.Close()
 	}
 	if err != nil {
-		return nil, fmt.Errorf("request error: %w", err)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &transportError{op: "request", err: err}
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, &transportError{op: "request", err: fmt.Errorf("unexpected status %s", resp.Status)}
 	}
 
 	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("body read error: %w", err)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &transportError{op: "body read", err: err}
 	}
+	inv.Body = data
 
 	var apiResponse APIResponse
 	if err := json.Unmarshal(data, &apiResponse); err != nil {
@@ -95,3 +283,22 @@ func (vk *VkAPI) MakeRequest(method string, params url.Values) (*APIResponse, er
 
 	return &apiResponse, nil
 }
+
+// Call sends m and decodes the response into T.
+func Call[T any](ctx context.Context, vk *VkAPI, m Method) (T, error) {
+	var r T
+	params, err := prepare(m)
+	if err != nil {
+		return r, err
+	}
+
+	resp, err := vk.MakeRequestContext(ctx, m.Name(), params)
+	if err != nil {
+		return r, err
+	}
+
+	if err := json.Unmarshal(resp.Response, &r); err != nil {
+		return r, err
+	}
+	return r, nil
+}
@@ -0,0 +1,140 @@
+package vkapi_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+	"github.com/seilem/vk-golang-sdk/vktest"
+)
+
+type getReq struct {
+	UserIDs []int `vk:"user_ids"`
+}
+
+func (getReq) Name() string {
+	return "users.get"
+}
+
+func TestCall(t *testing.T) {
+	tests := []struct {
+		name     string
+		response interface{}
+		err      error
+		want     []vkapi.User
+		wantErr  bool
+	}{
+		{"decoded", []vkapi.User{{ID: 1, FirstName: "Pavel"}}, nil, []vkapi.User{{ID: 1, FirstName: "Pavel"}}, false},
+		{"api error", nil, vkapi.ErrAccessDenied, nil, true},
+		{"wrong type", map[string]int{"count": 1}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := vktest.NewServer()
+			defer srv.Close()
+			srv.HandleFunc("users.get", func(url.Values) (interface{}, error) {
+				return tt.response, tt.err
+			})
+
+			users, err := vkapi.Call[[]vkapi.User](context.Background(), srv.Client("token"), getReq{UserIDs: []int{1, 2}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(users) != len(tt.want) || len(users) > 0 && users[0] != tt.want[0] {
+				t.Errorf("users = %+v, want %+v", users, tt.want)
+			}
+
+			params := srv.Requests("users.get")[0]
+			if params.Get("user_ids") != "1,2" || params.Get("v") != vkapi.APIVersion || params.Get("access_token") != "token" {
+				t.Errorf("params = %v", params)
+			}
+		})
+	}
+}
+
+func TestCallContextDone(t *testing.T) {
+	srv := vktest.NewServer()
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := vkapi.Call[[]vkapi.User](ctx, srv.Client("token"), getReq{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestCallEncodeError(t *testing.T) {
+	srv := vktest.NewServer()
+	defer srv.Close()
+	srv.Handle("messages.send", 1)
+
+	req := &vkapi.MsgReq{
+		PeerID:  1,
+		Message: "hi",
+		Template: &vkapi.Template{
+			Type:     "carousel",
+			Elements: []vkapi.TemplateElement{{Action: map[string]interface{}{"type": make(chan int)}}},
+		},
+	}
+	if _, err := vkapi.Call[int](context.Background(), srv.Client("token"), req); err == nil {
+		t.Error("err = nil, want an encoding error")
+	}
+	if n := len(srv.Requests("")); n != 0 {
+		t.Errorf("%d requests sent for a request that failed to encode", n)
+	}
+}
+
+func TestClientConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(vk *vkapi.VkAPI)
+		params    url.Values
+		want      url.Values
+	}{
+		{
+			name:      "defaults",
+			configure: func(*vkapi.VkAPI) {},
+			want:      url.Values{"v": {vkapi.APIVersion}},
+		},
+		{
+			name:      "version",
+			configure: func(vk *vkapi.VkAPI) { vk.Version = "5.131" },
+			want:      url.Values{"v": {"5.131"}},
+		},
+		{
+			name:      "default params",
+			configure: func(vk *vkapi.VkAPI) { vk.DefaultParams = url.Values{"lang": {"en"}, "test_mode": {"1"}} },
+			want:      url.Values{"lang": {"en"}, "test_mode": {"1"}},
+		},
+		{
+			name:      "request params win",
+			configure: func(vk *vkapi.VkAPI) { vk.DefaultParams = url.Values{"lang": {"en"}} },
+			params:    url.Values{"lang": {"ru"}, "v": {"5.100"}},
+			want:      url.Values{"lang": {"ru"}, "v": {"5.100"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := vktest.NewServer()
+			defer srv.Close()
+			srv.Handle("users.get", []vkapi.User{})
+
+			vk := vkapi.NewVkAPIWithClient("token", srv.Server.Client())
+			vk.BaseURL = srv.URL + "/method" // no trailing slash
+			tt.configure(vk)
+			if _, err := vk.MakeRequestContext(context.Background(), "users.get", tt.params); err != nil {
+				t.Fatal(err)
+			}
+
+			got := srv.Requests("users.get")[0]
+			for k := range tt.want {
+				if got.Get(k) != tt.want.Get(k) {
+					t.Errorf("%s = %q, want %q", k, got.Get(k), tt.want.Get(k))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package vkapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExecuteLimit is the maximum number of API calls in one execute request.
+const ExecuteLimit = 25
+
+// ErrExecuteCallFailed is returned for a call that failed within execute
+// when the response has no matching entry in execute_errors.
+var ErrExecuteCallFailed = errors.New("call failed within execute")
+
+var batchableMethod = regexp.MustCompile(`^[a-zA-Z]+\.[a-zA-Z]+$`)
+
+func batchable(method string) bool {
+	return method != "execute" && batchableMethod.MatchString(method)
+}
+
+// Batcher collects requests made within Window and sends them
+// as a single execute call.
+//
+// See https://vk.com/dev/execute
+type Batcher struct {
+	vk     *VkAPI
+	window time.Duration
+
+	mu      sync.Mutex
+	pending []*batchCall
+	timer   *time.Timer
+}
+
+type batchCall struct {
+	ctx    context.Context
+	method string
+	params url.Values
+	done   chan batchResult
+}
+
+type batchResult struct {
+	resp *APIResponse
+	err  error
+}
+
+// NewBatcher returns a batcher sending requests through vk.
+// Assign it to vk.Batcher to batch every request transparently.
+func NewBatcher(vk *VkAPI, window time.Duration) *Batcher {
+	return &Batcher{
+		vk:     vk,
+		window: window,
+	}
+}
+
+// Do adds m to the current batch and waits for its result.
+func (b *Batcher) Do(ctx context.Context, m Method) (*APIResponse, error) {
+	params, err := prepare(m)
+	if err != nil {
+		return nil, err
+	}
+	return b.enqueue(ctx, m.Name(), params)
+}
+
+func (b *Batcher) enqueue(ctx context.Context, method string, params url.Values) (*APIResponse, error) {
+	c := &batchCall{
+		ctx:    ctx,
+		method: method,
+		params: params,
+		done:   make(chan batchResult, 1),
+	}
+
+	b.mu.Lock()
+	b.pending = append(b.pending, c)
+	switch {
+	case len(b.pending) >= ExecuteLimit:
+		batch := b.take()
+		go b.flush(batch)
+	case len(b.pending) == 1:
+		b.timer = time.AfterFunc(b.window, b.flushPending)
+	}
+	b.mu.Unlock()
+
+	select {
+	case r := <-c.done:
+		return r.resp, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// take must be called with b.mu held.
+func (b *Batcher) take() []*batchCall {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	batch := b.pending
+	b.pending = nil
+	return batch
+}
+
+func (b *Batcher) flushPending() {
+	b.mu.Lock()
+	batch := b.take()
+	b.mu.Unlock()
+
+	if len(batch) > 0 {
+		b.flush(batch)
+	}
+}
+
+func (b *Batcher) flush(batch []*batchCall) {
+	// Calls whose callers have given up are not worth a slot in execute.
+	live := batch[:0]
+	for _, c := range batch {
+		if err := c.ctx.Err(); err != nil {
+			c.done <- batchResult{err: err}
+			continue
+		}
+		live = append(live, c)
+	}
+	batch = live
+
+	switch len(batch) {
+	case 0:
+		return
+	case 1:
+		c := batch[0]
+		resp, err := b.vk.send(c.ctx, c.method, c.params, true)
+		c.done <- batchResult{resp, err}
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for _, c := range batch {
+			select {
+			case <-c.ctx.Done():
+			case <-ctx.Done():
+				return
+			}
+		}
+		cancel()
+	}()
+
+	code, retry, err := compileExecute(batch)
+	if err != nil {
+		for _, c := range batch {
+			c.done <- batchResult{err: err}
+		}
+		return
+	}
+
+	resp, err := b.vk.send(ctx, "execute", url.Values{"code": {code}}, retry)
+	if err != nil {
+		for _, c := range batch {
+			c.done <- batchResult{err: err}
+		}
+		return
+	}
+
+	methods := make([]string, len(batch))
+	for i, c := range batch {
+		methods[i] = c.method
+	}
+	for i, r := range splitExecute(resp, methods) {
+		batch[i].done <- r
+	}
+}
+
+// compileExecute builds VKScript code returning the results of all calls
+// as an array. It also reports whether the batch is safe to retry.
+func compileExecute(batch []*batchCall) (string, bool, error) {
+	retry := true
+	calls := make([]string, 0, len(batch))
+	for _, c := range batch {
+		args := make(map[string]string, len(c.params))
+		for k, v := range c.params {
+			if k == "v" || k == "access_token" {
+				continue
+			}
+			args[k] = strings.Join(v, ",")
+		}
+
+		buf := &bytes.Buffer{}
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(args); err != nil {
+			return "", false, fmt.Errorf("execute compile error: %w", err)
+		}
+		calls = append(calls, fmt.Sprintf("API.%s(%s)", c.method, bytes.TrimSpace(buf.Bytes())))
+
+		if !idempotent(c.method, c.params) {
+			retry = false
+		}
+	}
+	return "return [" + strings.Join(calls, ",") + "];", retry, nil
+}
+
+// splitExecute matches every item of the execute response with its call.
+// Failed calls return false and get the next entry of execute_errors
+// for their method, or ErrExecuteCallFailed if there is none.
+func splitExecute(resp *APIResponse, methods []string) []batchResult {
+	n := len(methods)
+	results := make([]batchResult, n)
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(resp.Response, &items); err != nil || len(items) != n {
+		if err == nil {
+			err = fmt.Errorf("execute returned %d results for %d calls", len(items), n)
+		}
+		for i := range results {
+			results[i].err = err
+		}
+		return results
+	}
+
+	var errs []json.RawMessage
+	if len(resp.ExecuteErrors) > 0 {
+		_ = json.Unmarshal(resp.ExecuteErrors, &errs)
+	}
+
+	for i, item := range items {
+		if string(item) != "false" {
+			results[i].resp = &APIResponse{Response: item}
+			continue
+		}
+
+		results[i].err = fmt.Errorf("%s: %w", methods[i], ErrExecuteCallFailed)
+		for j, raw := range errs {
+			var e APIError
+			if json.Unmarshal(raw, &e) != nil || e.Method != methods[i] {
+				continue
+			}
+			e.Raw = raw
+			results[i].err = e
+			errs = errs[j+1:]
+			break
+		}
+	}
+	return results
+}
@@ -0,0 +1,140 @@
+package vkapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSplitExecute(t *testing.T) {
+	accessDenied := `{"method":"users.get","error_code":15,"error_msg":"Access denied"}`
+	wallErr := `{"method":"wall.get","error_code":18,"error_msg":"User was deleted"}`
+
+	tests := []struct {
+		name     string
+		methods  []string
+		response string
+		errors   string
+		want     []string // response item or the error wanted with errors.Is
+	}{
+		{
+			name:     "all ok",
+			methods:  []string{"users.get", "groups.getById"},
+			response: `[[{"id":1}],[{"id":2}]]`,
+			want:     []string{`[{"id":1}]`, `[{"id":2}]`},
+		},
+		{
+			name:     "error matched to call",
+			methods:  []string{"users.get", "users.get", "users.get"},
+			response: `[[{"id":1}],false,[{"id":3}]]`,
+			errors:   "[" + accessDenied + "]",
+			want:     []string{`[{"id":1}]`, "15", `[{"id":3}]`},
+		},
+		{
+			name:     "false without execute_errors",
+			methods:  []string{"users.get", "users.get", "users.get"},
+			response: `[[{"id":1}],false,[{"id":3}]]`,
+			want:     []string{`[{"id":1}]`, "failed", `[{"id":3}]`},
+		},
+		{
+			name:     "more false than errors",
+			methods:  []string{"users.get", "users.get"},
+			response: `[false,false]`,
+			errors:   "[" + accessDenied + "]",
+			want:     []string{"15", "failed"},
+		},
+		{
+			name:     "errors matched by method",
+			methods:  []string{"users.get", "wall.get"},
+			response: `[false,false]`,
+			errors:   "[" + wallErr + "]",
+			want:     []string{"failed", "18"},
+		},
+		{
+			name:     "wrong number of items",
+			methods:  []string{"users.get", "wall.get"},
+			response: `[1]`,
+			want:     []string{"count", "count"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &APIResponse{Response: json.RawMessage(tt.response)}
+			if tt.errors != "" {
+				resp.ExecuteErrors = json.RawMessage(tt.errors)
+			}
+
+			results := splitExecute(resp, tt.methods)
+			for i, want := range tt.want {
+				r := results[i]
+				switch want {
+				case "failed":
+					if !errors.Is(r.err, ErrExecuteCallFailed) {
+						t.Errorf("call %d: err = %v, want %v", i, r.err, ErrExecuteCallFailed)
+					}
+				case "count":
+					if r.err == nil || !strings.Contains(r.err.Error(), "results for") {
+						t.Errorf("call %d: err = %v, want count mismatch", i, r.err)
+					}
+				case "15":
+					if !errors.Is(r.err, ErrAccessDenied) {
+						t.Errorf("call %d: err = %v, want %v", i, r.err, ErrAccessDenied)
+					}
+				case "18":
+					if !errors.Is(r.err, ErrUserDeleted) {
+						t.Errorf("call %d: err = %v, want %v", i, r.err, ErrUserDeleted)
+					}
+				default:
+					if r.err != nil {
+						t.Errorf("call %d: unexpected error %v", i, r.err)
+					} else if string(r.resp.Response) != want {
+						t.Errorf("call %d: response = %s, want %s", i, r.resp.Response, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCompileExecute(t *testing.T) {
+	tests := []struct {
+		name      string
+		calls     []*batchCall
+		code      string
+		wantRetry bool
+	}{
+		{
+			name: "read methods",
+			calls: []*batchCall{
+				{method: "users.get", params: url.Values{"user_ids": {"1,2"}, "v": {APIVersion}}},
+				{method: "groups.getById", params: url.Values{"group_id": {"<b>"}, "access_token": {"t"}}},
+			},
+			code:      `return [API.users.get({"user_ids":"1,2"}),API.groups.getById({"group_id":"<b>"})];`,
+			wantRetry: true,
+		},
+		{
+			name: "send without random_id",
+			calls: []*batchCall{
+				{method: "messages.send", params: url.Values{"peer_id": {"1"}}},
+			},
+			code:      `return [API.messages.send({"peer_id":"1"})];`,
+			wantRetry: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, retry, err := compileExecute(tt.calls)
+			if err != nil {
+				t.Fatalf("compileExecute: %v", err)
+			}
+			if code != tt.code {
+				t.Errorf("code = %s, want %s", code, tt.code)
+			}
+			if retry != tt.wantRetry {
+				t.Errorf("retry = %v, want %v", retry, tt.wantRetry)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package vkapi_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+	"github.com/seilem/vk-golang-sdk/vktest"
+)
+
+func TestBatcher(t *testing.T) {
+	tests := []struct {
+		name        string
+		ids         []string
+		wantExecute int
+		wantErr     map[string]vkapi.ErrorCode
+	}{
+		{"single call goes direct", []string{"1"}, 0, nil},
+		{"merged", []string{"1", "2", "3"}, 1, nil},
+		{"failed call", []string{"1", "0", "3"}, 1, map[string]vkapi.ErrorCode{"0": vkapi.ErrAccessDenied}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := vktest.NewServer()
+			defer srv.Close()
+			srv.HandleFunc("users.get", func(params url.Values) (interface{}, error) {
+				if params.Get("user_ids") == "0" {
+					return nil, vkapi.ErrAccessDenied
+				}
+				return params.Get("user_ids"), nil
+			})
+
+			vk := srv.Client("token")
+			vk.Batcher = vkapi.NewBatcher(vk, 20*time.Millisecond)
+
+			var wg sync.WaitGroup
+			for _, id := range tt.ids {
+				wg.Add(1)
+				go func(id string) {
+					defer wg.Done()
+					resp, err := vk.MakeRequestContext(context.Background(), "users.get", url.Values{"user_ids": {id}})
+					if code, ok := tt.wantErr[id]; ok {
+						var e vkapi.APIError
+						if !errors.As(err, &e) || !errors.Is(err, code) || e.Method != "users.get" {
+							t.Errorf("user %s: err = %v, want %v", id, err, code)
+						}
+						return
+					}
+					if err != nil {
+						t.Errorf("user %s: %v", id, err)
+						return
+					}
+					if got := string(resp.Response); got != `"`+id+`"` {
+						t.Errorf("user %s: response = %s", id, got)
+					}
+				}(id)
+			}
+			wg.Wait()
+
+			if n := len(srv.Requests("execute")); n != tt.wantExecute {
+				t.Errorf("execute sent %d times, want %d", n, tt.wantExecute)
+			}
+			if n := len(srv.Requests("users.get")); n != len(tt.ids) {
+				t.Errorf("users.get served %d times, want %d", n, len(tt.ids))
+			}
+		})
+	}
+}
+
+func TestBatcherContextDone(t *testing.T) {
+	srv := vktest.NewServer()
+	defer srv.Close()
+	srv.HandleFunc("users.get", func(params url.Values) (interface{}, error) {
+		return params.Get("user_ids"), nil
+	})
+
+	vk := srv.Client("token")
+	vk.Batcher = vkapi.NewBatcher(vk, 100*time.Millisecond)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := vk.MakeRequestContext(ctx, "users.get", url.Values{"user_ids": {"2"}}); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+		}
+	}()
+
+	resp, err := vk.MakeRequestContext(context.Background(), "users.get", url.Values{"user_ids": {"1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(resp.Response); got != `"1"` {
+		t.Errorf("response = %s", got)
+	}
+	wg.Wait()
+
+	if n := len(srv.Requests("execute")); n != 0 {
+		t.Errorf("execute sent %d times, want 0", n)
+	}
+	for _, params := range srv.Requests("users.get") {
+		if params.Get("user_ids") == "2" {
+			t.Error("cancelled call was sent")
+		}
+	}
+}
@@ -1,112 +1,139 @@
 package vkapi
 
 import (
-	"encoding/json"
+	"context"
 	"net/url"
-	"strconv"
-	"strings"
 )
 
 type OpenTopicReq struct {
-	GroupID int64
-	TopicID int64
+	GroupID int64 `vk:"group_id"`
+	TopicID int64 `vk:"topic_id"`
 }
 
 func (OpenTopicReq) Name() string {
 	return "board.openTopic"
 }
 
+// EncodeValues returns the params of r.
+func (r OpenTopicReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(r)
+}
+
+// Values returns the params of r.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
 func (r OpenTopicReq) Values() url.Values {
-	v := url.Values{}
-	v.Set("group_id", strconv.FormatInt(r.GroupID, 10))
-	v.Set("topic_id", strconv.FormatInt(r.TopicID, 10))
-	return v
+	values, _ := EncodeValues(r)
+	return values
+}
+
+func (r OpenTopicReq) Validate() error {
+	return validate(r.Name(),
+		required("group_id", r.GroupID > 0),
+		required("topic_id", r.TopicID > 0),
+	)
 }
 
 // Re-opens a previously closed topic on a community's discussion board.
 //
 // See https://vk.com/dev/board.openTopic
 func (vk *VkAPI) OpenTopic(r *OpenTopicReq) error {
-	_, err := vk.MakeRequest(r.Name(), r.Values())
-	if err != nil {
-		return err
-	}
-	return nil
+	return vk.OpenTopicContext(context.Background(), r)
+}
+
+// OpenTopicContext is like OpenTopic but uses ctx for the request.
+func (vk *VkAPI) OpenTopicContext(ctx context.Context, r *OpenTopicReq) error {
+	_, err := Call[int](ctx, vk, r)
+	return err
 }
 
 type CloseTopicReq struct {
-	GroupID int64
-	TopicID int64
+	GroupID int64 `vk:"group_id"`
+	TopicID int64 `vk:"topic_id"`
 }
 
 func (CloseTopicReq) Name() string {
 	return "board.closeTopic"
 }
 
+// EncodeValues returns the params of r.
+func (r CloseTopicReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(r)
+}
+
+// Values returns the params of r.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
 func (r CloseTopicReq) Values() url.Values {
-	v := url.Values{}
-	v.Set("group_id", strconv.FormatInt(r.GroupID, 10))
-	v.Set("topic_id", strconv.FormatInt(r.TopicID, 10))
-	return v
+	values, _ := EncodeValues(r)
+	return values
+}
+
+func (r CloseTopicReq) Validate() error {
+	return validate(r.Name(),
+		required("group_id", r.GroupID > 0),
+		required("topic_id", r.TopicID > 0),
+	)
 }
 
 // Closes a topic on a community's discussion board so that comments cannot be posted.
 //
 // See https://vk.com/dev/board.closeTopic
 func (vk *VkAPI) CloseTopic(r *CloseTopicReq) error {
-	_, err := vk.MakeRequest(r.Name(), r.Values())
-	if err != nil {
-		return err
-	}
-	return nil
+	return vk.CloseTopicContext(context.Background(), r)
+}
+
+// CloseTopicContext is like CloseTopic but uses ctx for the request.
+func (vk *VkAPI) CloseTopicContext(ctx context.Context, r *CloseTopicReq) error {
+	_, err := Call[int](ctx, vk, r)
+	return err
 }
 
 type CreateCommentReq struct {
-	GroupID     int64
-	TopicID     int64
-	Message     string
-	Attachments []string
-	FromGroup   bool
-	StickerID   int
-	GUID        string
+	GroupID     int64    `vk:"group_id"`
+	TopicID     int64    `vk:"topic_id"`
+	Message     string   `vk:"message,omitempty"`
+	Attachments []string `vk:"attachments,omitempty"`
+	FromGroup   bool     `vk:"from_group,omitempty"`
+	StickerID   int      `vk:"sticker_id,omitempty"`
+	GUID        string   `vk:"guid,omitempty"`
 }
 
 func (CreateCommentReq) Name() string {
 	return "board.createComment"
 }
 
+// EncodeValues returns the params of r.
+func (r CreateCommentReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(r)
+}
+
+// Values returns the params of r.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
 func (r CreateCommentReq) Values() url.Values {
-	v := url.Values{}
-	v.Set("group_id", strconv.FormatInt(r.GroupID, 10))
-	v.Set("topic_id", strconv.FormatInt(r.TopicID, 10))
-	if len(r.Message) > 0 {
-		v.Set("message", r.Message)
-	}
-	if len(r.Attachments) > 0 {
-		v.Set("attachment", strings.Join(r.Attachments, ","))
-	}
-	v.Set("from_group", strconv.Itoa(btoi(r.FromGroup)))
-	if r.StickerID > 0 {
-		v.Set("sticker_id", strconv.Itoa(r.StickerID))
-	}
-	if len(r.GUID) > 0 {
-		v.Set("guid", r.GUID)
-	}
-	return v
+	values, _ := EncodeValues(r)
+	return values
+}
+
+func (r CreateCommentReq) Validate() error {
+	return validate(r.Name(),
+		required("group_id", r.GroupID > 0),
+		required("topic_id", r.TopicID > 0),
+		requiredOneOf("message, attachments, sticker_id",
+			r.Message != "" || len(r.Attachments) > 0 || r.StickerID != 0),
+		maxItems("attachments", len(r.Attachments), MaxAttachments),
+	)
 }
 
 // Adds a comment on a topic on a community's discussion board.
 //
 // See https://vk.com/dev/board.createComment
 func (vk *VkAPI) CreateComment(r *CreateCommentReq) (int64, error) {
-	resp, err := vk.MakeRequest(r.Name(), r.Values())
-	if err != nil {
-		return 0, err
-	}
-
-	var id int64
-	if err := json.Unmarshal(resp.Response, &id); err != nil {
-		return 0, err
-	}
-	return id, nil
+	return vk.CreateCommentContext(context.Background(), r)
+}
+
+// CreateCommentContext is like CreateComment but uses ctx for the request.
+func (vk *VkAPI) CreateCommentContext(ctx context.Context, r *CreateCommentReq) (int64, error) {
+	return Call[int64](ctx, vk, r)
 }
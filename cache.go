@@ -0,0 +1,201 @@
+package vkapi
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStore keeps cached responses. Implementations must be safe for
+// concurrent use.
+type CacheStore interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+	// DeletePrefix drops every entry whose key starts with prefix.
+	DeletePrefix(prefix string)
+}
+
+// ResponseCache caches responses of read-only methods. Keys are made
+// of the method name, its sorted params and a hash of the token, so a
+// cache may be shared by clients with different tokens. Clients with
+// a TokenSource other than StaticTokenSource only invalidate entries:
+// the token of their requests is not known before they are sent.
+type ResponseCache struct {
+	Store CacheStore
+	// TTL lists cacheable methods with the lifetime of their responses.
+	TTL map[string]time.Duration
+	// Invalidates maps write methods to the methods whose cached
+	// responses they make stale.
+	Invalidates map[string][]string
+
+	mu sync.Mutex
+	// gens counts invalidations of each method, so that a response
+	// requested before one is not stored after it.
+	gens map[string]uint64
+}
+
+// NewResponseCache returns a cache keeping up to size responses in
+// memory with default TTLs for users.get, groups.getById, video.get
+// and storage.get.
+func NewResponseCache(size int) *ResponseCache {
+	return &ResponseCache{
+		Store: NewLRUStore(size),
+		TTL: map[string]time.Duration{
+			"users.get":      5 * time.Minute,
+			"groups.getById": 10 * time.Minute,
+			"video.get":      time.Minute,
+			"storage.get":    30 * time.Second,
+		},
+		Invalidates: map[string][]string{
+			"storage.set": {"storage.get"},
+		},
+	}
+}
+
+func cacheKey(method string, params url.Values, token string) string {
+	p := make(url.Values, len(params))
+	for k, v := range params {
+		if k == "captcha_sid" || k == "captcha_key" {
+			continue
+		}
+		p[k] = v
+	}
+	sum := sha256.Sum256([]byte(token))
+	return method + "?" + p.Encode() + "#" + hex.EncodeToString(sum[:8])
+}
+
+func (c *ResponseCache) get(method, key string) (*APIResponse, bool) {
+	if _, ok := c.TTL[method]; !ok {
+		return nil, false
+	}
+	data, ok := c.Store.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return &APIResponse{Response: data}, true
+}
+
+// generation returns the number of invalidations of method so far.
+func (c *ResponseCache) generation(method string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gens[method]
+}
+
+// setKey stores resp under key computed before the request was sent,
+// since sending adds params like v to the original ones. resp is
+// dropped if method was invalidated since gen was taken.
+func (c *ResponseCache) setKey(method, key string, gen uint64, resp *APIResponse) {
+	ttl, ok := c.TTL[method]
+	if !ok || ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gens[method] == gen {
+		c.Store.Set(key, resp.Response, ttl)
+	}
+}
+
+func (c *ResponseCache) invalidate(method string) {
+	methods := c.Invalidates[method]
+	if len(methods) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gens == nil {
+		c.gens = make(map[string]uint64)
+	}
+	for _, m := range methods {
+		c.gens[m]++
+		c.Store.DeletePrefix(m + "?")
+	}
+}
+
+// LRUStore is an in-memory CacheStore evicting the least recently used
+// entries once it holds more than its size.
+type LRUStore struct {
+	size int
+
+	mu      sync.Mutex
+	ll      *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewLRUStore returns a store holding up to size entries.
+func NewLRUStore(size int) *LRUStore {
+	if size < 1 {
+		size = 1
+	}
+	return &LRUStore{
+		size:    size,
+		ll:      list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (s *LRUStore) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if time.Now().After(e.expires) {
+		s.remove(el)
+		return nil, false
+	}
+	s.ll.MoveToFront(el)
+	return e.value, true
+}
+
+func (s *LRUStore) Set(key string, value []byte, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expires := time.Now().Add(ttl)
+	if el, ok := s.entries[key]; ok {
+		e := el.Value.(*lruEntry)
+		e.value = value
+		e.expires = expires
+		s.ll.MoveToFront(el)
+		return
+	}
+
+	s.entries[key] = s.ll.PushFront(&lruEntry{key: key, value: value, expires: expires})
+	for s.ll.Len() > s.size {
+		s.remove(s.ll.Back())
+	}
+}
+
+func (s *LRUStore) DeletePrefix(prefix string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, el := range s.entries {
+		if strings.HasPrefix(key, prefix) {
+			s.remove(el)
+		}
+	}
+}
+
+// remove must be called with s.mu held.
+func (s *LRUStore) remove(el *list.Element) {
+	s.ll.Remove(el)
+	delete(s.entries, el.Value.(*lruEntry).key)
+}
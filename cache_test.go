@@ -0,0 +1,217 @@
+package vkapi_test
+
+import (
+	"context"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+	"github.com/seilem/vk-golang-sdk/vktest"
+)
+
+func TestResponseCache(t *testing.T) {
+	tests := []struct {
+		name  string
+		calls []func(cache *vkapi.ResponseCache, srv *vktest.Server) *vkapi.VkAPI
+		// want is the response of each call.
+		want []string
+	}{
+		{
+			name: "hit",
+			calls: []func(*vkapi.ResponseCache, *vktest.Server) *vkapi.VkAPI{
+				cachedClient("aaaa", nil), cachedClient("aaaa", nil),
+			},
+			want: []string{`"aaaa1"`, `"aaaa1"`},
+		},
+		{
+			name: "other token",
+			calls: []func(*vkapi.ResponseCache, *vktest.Server) *vkapi.VkAPI{
+				cachedClient("aaaa", nil), cachedClient("bbbb", nil), cachedClient("aaaa", nil),
+			},
+			want: []string{`"aaaa1"`, `"bbbb2"`, `"aaaa1"`},
+		},
+		{
+			name: "static token source",
+			calls: []func(*vkapi.ResponseCache, *vktest.Server) *vkapi.VkAPI{
+				cachedClient("", vkapi.StaticTokenSource("aaaa")), cachedClient("aaaa", nil),
+			},
+			want: []string{`"aaaa1"`, `"aaaa1"`},
+		},
+		{
+			name: "token source bypasses cache",
+			calls: []func(*vkapi.ResponseCache, *vktest.Server) *vkapi.VkAPI{
+				cachedClient("", vkapi.NewTokenPool(0, "aaaa")), cachedClient("", vkapi.NewTokenPool(0, "bbbb")),
+			},
+			want: []string{`"aaaa1"`, `"bbbb2"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := vktest.NewServer()
+			defer srv.Close()
+			var n int32
+			srv.HandleFunc("users.get", func(params url.Values) (interface{}, error) {
+				return params.Get("access_token") + string(rune('0'+atomic.AddInt32(&n, 1))), nil
+			})
+
+			cache := vkapi.NewResponseCache(10)
+			for i, call := range tt.calls {
+				vk := call(cache, srv)
+				resp, err := vk.MakeRequestContext(context.Background(), "users.get", url.Values{"user_ids": {"1"}})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := string(resp.Response); got != tt.want[i] {
+					t.Errorf("call #%d = %s, want %s", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func cachedClient(token string, source vkapi.TokenSource) func(*vkapi.ResponseCache, *vktest.Server) *vkapi.VkAPI {
+	return func(cache *vkapi.ResponseCache, srv *vktest.Server) *vkapi.VkAPI {
+		vk := srv.Client(token)
+		vk.TokenSource = source
+		vk.Cache = cache
+		return vk
+	}
+}
+
+func TestResponseCacheInvalidate(t *testing.T) {
+	srv := vktest.NewServer()
+	defer srv.Close()
+
+	var value atomic.Value
+	value.Store("old")
+	srv.HandleFunc("storage.get", func(url.Values) (interface{}, error) {
+		return value.Load(), nil
+	})
+	srv.HandleFunc("storage.set", func(params url.Values) (interface{}, error) {
+		value.Store(params.Get("value"))
+		return 1, nil
+	})
+
+	vk := srv.Client("token")
+	vk.Cache = vkapi.NewResponseCache(10)
+	ctx := context.Background()
+	get := func() string {
+		resp, err := vk.MakeRequestContext(ctx, "storage.get", url.Values{"key": {"k"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(resp.Response)
+	}
+
+	if got := get(); got != `"old"` {
+		t.Fatalf("storage.get = %s", got)
+	}
+	if _, err := vk.MakeRequestContext(ctx, "storage.set", url.Values{"key": {"k"}, "value": {"new"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := get(); got != `"new"` {
+		t.Errorf("storage.get after storage.set = %s, want %q", got, "new")
+	}
+}
+
+func TestResponseCacheInFlightRead(t *testing.T) {
+	srv := vktest.NewServer()
+	defer srv.Close()
+
+	var value atomic.Value
+	value.Store("old")
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv.HandleFunc("storage.get", func(url.Values) (interface{}, error) {
+		v := value.Load()
+		select {
+		case started <- struct{}{}:
+			<-release
+		default:
+		}
+		return v, nil
+	})
+	srv.HandleFunc("storage.set", func(params url.Values) (interface{}, error) {
+		value.Store(params.Get("value"))
+		return 1, nil
+	})
+
+	vk := srv.Client("token")
+	vk.Cache = vkapi.NewResponseCache(10)
+	ctx := context.Background()
+	params := url.Values{"key": {"k"}}
+
+	// The read starts before the write and completes after it.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		vk.MakeRequestContext(ctx, "storage.get", params)
+	}()
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("storage.get was not sent")
+	}
+	if _, err := vk.MakeRequestContext(ctx, "storage.set", url.Values{"key": {"k"}, "value": {"new"}}); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	<-done
+
+	resp, err := vk.MakeRequestContext(ctx, "storage.get", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(resp.Response); got != `"new"` {
+		t.Errorf("storage.get = %s, want the value written after the in-flight read", got)
+	}
+}
+
+func TestLRUStore(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(s *vkapi.LRUStore)
+		want map[string]bool
+	}{
+		{
+			name: "evicts least recently used",
+			run: func(s *vkapi.LRUStore) {
+				s.Set("a", nil, time.Minute)
+				s.Set("b", nil, time.Minute)
+				s.Get("a")
+				s.Set("c", nil, time.Minute)
+			},
+			want: map[string]bool{"a": true, "b": false, "c": true},
+		},
+		{
+			name: "expires",
+			run: func(s *vkapi.LRUStore) {
+				s.Set("a", nil, -time.Second)
+				s.Set("b", nil, time.Minute)
+			},
+			want: map[string]bool{"a": false, "b": true},
+		},
+		{
+			name: "delete prefix",
+			run: func(s *vkapi.LRUStore) {
+				s.Set("storage.get?key=a", nil, time.Minute)
+				s.Set("users.get?user_ids=1", nil, time.Minute)
+				s.DeletePrefix("storage.get?")
+			},
+			want: map[string]bool{"storage.get?key=a": false, "users.get?user_ids=1": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := vkapi.NewLRUStore(2)
+			tt.run(s)
+			for key, want := range tt.want {
+				if _, ok := s.Get(key); ok != want {
+					t.Errorf("Get(%q) found %v, want %v", key, ok, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package vkapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+)
+
+// EventConfirmation is sent by the Callback API to confirm the server
+// address.
+const EventConfirmation = "confirmation"
+
+// maxCallbackBody limits the size of a Callback API request.
+const maxCallbackBody = 1 << 20
+
+// CallbackHandler receives community events from the Callback API and
+// passes them to the same callbacks as GroupLongPoll, so a bot can
+// switch between the two without other changes.
+//
+// Events are handled after "ok" is sent, on vk.Dispatcher if it is set
+// and in a new goroutine otherwise. Set a Dispatcher to keep events of
+// one conversation in order.
+//
+// See https://vk.com/dev/callback_api
+type CallbackHandler struct {
+	// GroupID, if set, rejects events of other communities.
+	GroupID int64
+	// Confirmation is returned for confirmation events.
+	Confirmation string
+	// Secret, if set, must match the secret key of every event.
+	Secret string
+
+	vk *VkAPI
+}
+
+type callbackRequest struct {
+	GroupLPUpdates
+	Secret string `json:"secret"`
+}
+
+// NewCallbackHandler returns a handler for the community groupID that
+// confirms the server with the given string.
+func NewCallbackHandler(vk *VkAPI, groupID int64, confirmation string) *CallbackHandler {
+	return &CallbackHandler{
+		GroupID:      groupID,
+		Confirmation: confirmation,
+		vk:           vk,
+	}
+}
+
+func (h *CallbackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	log := h.vk.logger()
+	var req callbackRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCallbackBody)).Decode(&req); err != nil {
+		log.Warn("callback request decode failed", "error", err)
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if h.Secret != "" && subtle.ConstantTimeCompare([]byte(req.Secret), []byte(h.Secret)) != 1 {
+		log.Warn("callback request with wrong secret", "type", req.Type, "group_id", req.GroupID)
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if h.GroupID != 0 && int64(req.GroupID) != h.GroupID {
+		log.Warn("callback request for other group", "type", req.Type, "group_id", req.GroupID)
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	if req.Type == EventConfirmation {
+		w.Write([]byte(h.Confirmation))
+		return
+	}
+
+	update := &req.GroupLPUpdates
+	ctx := context.WithoutCancel(r.Context())
+	handle := func() {
+		h.vk.handleGroupLPCallback(ctx, update.Type, update)
+	}
+	if h.vk.Dispatcher == nil {
+		go handle()
+	} else if err := h.vk.Dispatcher.Dispatch(r.Context(), update.orderKey(), handle); err != nil {
+		// Not answering "ok" makes the API send the event again.
+		log.Error("callback event dispatch failed", "type", update.Type, "event_id", update.EventID, "error", err)
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok"))
+}
@@ -0,0 +1,134 @@
+package vkapi_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+)
+
+func TestCallbackHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		closed     bool
+		wantStatus int
+		wantBody   string
+		wantEvent  bool
+	}{
+		{
+			name:       "confirmation",
+			body:       `{"type":"confirmation","group_id":1,"secret":"s3cret"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   "abc123",
+		},
+		{
+			name:       "event",
+			body:       `{"type":"message_new","group_id":1,"event_id":"e1","secret":"s3cret","object":{"message":{"id":7,"peer_id":2}}}`,
+			wantStatus: http.StatusOK,
+			wantBody:   "ok",
+			wantEvent:  true,
+		},
+		{
+			name:       "wrong secret",
+			body:       `{"type":"message_new","group_id":1,"secret":"guess","object":{}}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "other group",
+			body:       `{"type":"message_new","group_id":2,"secret":"s3cret","object":{}}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "malformed",
+			body:       `{"type":`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "get",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "dispatcher closed",
+			body:       `{"type":"message_new","group_id":1,"secret":"s3cret","object":{}}`,
+			closed:     true,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vk := vkapi.NewVkAPI("token")
+			vk.Dispatcher = vkapi.NewDispatcher(1, 1)
+			if tt.closed {
+				vk.Dispatcher.Close()
+			}
+
+			events := make(chan *vkapi.NewMessage, 1)
+			vk.OnMessageNew(func(_ context.Context, m *vkapi.NewMessage) { events <- m })
+
+			h := vkapi.NewCallbackHandler(vk, 1, "abc123")
+			h.Secret = "s3cret"
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(method, "/callback", strings.NewReader(tt.body)))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+
+			vk.Dispatcher.Close()
+			select {
+			case m := <-events:
+				if !tt.wantEvent || m.Message.ID != 7 {
+					t.Errorf("handled %+v, want event %v", m, tt.wantEvent)
+				}
+			default:
+				if tt.wantEvent {
+					t.Error("event was not handled")
+				}
+			}
+		})
+	}
+}
+
+func TestCallbackHandlerWithoutDispatcher(t *testing.T) {
+	vk := vkapi.NewVkAPI("token")
+	handled := make(chan string, 1)
+	vk.OnAnyGroupEvent(func(_ context.Context, e *vkapi.GroupLPUpdates) { handled <- e.EventID })
+
+	srv := httptest.NewServer(vkapi.NewCallbackHandler(vk, 0, "abc123"))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL, "application/json", strings.NewReader(`{"type":"group_join","group_id":5,"event_id":"e2","object":{"user_id":1}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Errorf("body = %q, want ok", body)
+	}
+
+	select {
+	case id := <-handled:
+		if id != "e2" {
+			t.Errorf("handled event %q, want e2", id)
+		}
+	case <-time.After(time.Second):
+		t.Error("event was not handled")
+	}
+}
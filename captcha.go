@@ -0,0 +1,76 @@
+package vkapi
+
+import (
+	"context"
+	"errors"
+	"net/url"
+)
+
+// maxChallenges limits how many captcha or validation challenges
+// are answered for a single request.
+const maxChallenges = 3
+
+// CaptchaHandler solves captchas requested by the API.
+//
+// See https://vk.com/dev/captcha_error
+type CaptchaHandler interface {
+	// HandleCaptcha returns the text shown on the image at img.
+	HandleCaptcha(ctx context.Context, sid, img string) (key string, err error)
+}
+
+// CaptchaHandlerFunc adapts a function to CaptchaHandler.
+type CaptchaHandlerFunc func(ctx context.Context, sid, img string) (string, error)
+
+func (f CaptchaHandlerFunc) HandleCaptcha(ctx context.Context, sid, img string) (string, error) {
+	return f(ctx, sid, img)
+}
+
+// ValidationHandler lets the user pass validation at redirectURI.
+// The request is resent once HandleValidation returns nil.
+//
+// See https://vk.com/dev/need_validation
+type ValidationHandler interface {
+	HandleValidation(ctx context.Context, redirectURI string) error
+}
+
+// ValidationHandlerFunc adapts a function to ValidationHandler.
+type ValidationHandlerFunc func(ctx context.Context, redirectURI string) error
+
+func (f ValidationHandlerFunc) HandleValidation(ctx context.Context, redirectURI string) error {
+	return f(ctx, redirectURI)
+}
+
+// challenge resends the request after the captcha or validation
+// described by err is handled. It returns err unchanged if there is
+// no handler for it.
+func (vk *VkAPI) challenge(ctx context.Context, method string, params url.Values, retry bool, err error) (*APIResponse, error) {
+	for i := 0; i < maxChallenges; i++ {
+		var e APIError
+		if !errors.As(err, &e) {
+			return nil, err
+		}
+
+		switch {
+		case e.Is(ErrCaptchaNeeded) && vk.CaptchaHandler != nil:
+			key, herr := vk.CaptchaHandler.HandleCaptcha(ctx, e.CaptchaSID, e.CaptchaImg)
+			if herr != nil {
+				return nil, herr
+			}
+			params.Set("captcha_sid", e.CaptchaSID)
+			params.Set("captcha_key", key)
+		case e.Is(ErrValidationRequired) && vk.ValidationHandler != nil:
+			if herr := vk.ValidationHandler.HandleValidation(ctx, e.RedirectURI); herr != nil {
+				return nil, herr
+			}
+		default:
+			return nil, err
+		}
+
+		var resp *APIResponse
+		resp, err = vk.send(ctx, method, params, retry)
+		if err == nil {
+			return resp, nil
+		}
+	}
+	return nil, err
+}
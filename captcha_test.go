@@ -0,0 +1,87 @@
+package vkapi_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+	"github.com/seilem/vk-golang-sdk/vktest"
+)
+
+func TestCaptchaHandler(t *testing.T) {
+	captcha := vkapi.APIError{Code: int(vkapi.ErrCaptchaNeeded), Msg: "Captcha needed", CaptchaSID: "sid", CaptchaImg: "img"}
+	handlerErr := errors.New("no solver")
+
+	tests := []struct {
+		name      string
+		captchas  int // number of captchas before the method succeeds
+		handler   vkapi.CaptchaHandler
+		wantErr   error
+		wantCalls int
+	}{
+		{"no handler", 1, nil, vkapi.ErrCaptchaNeeded, 1},
+		{"solved", 1, vkapi.CaptchaHandlerFunc(func(ctx context.Context, sid, img string) (string, error) {
+			return "key-" + sid, nil
+		}), nil, 2},
+		{"handler failed", 1, vkapi.CaptchaHandlerFunc(func(ctx context.Context, sid, img string) (string, error) {
+			return "", handlerErr
+		}), handlerErr, 1},
+		{"too many captchas", 10, vkapi.CaptchaHandlerFunc(func(ctx context.Context, sid, img string) (string, error) {
+			return "key", nil
+		}), vkapi.ErrCaptchaNeeded, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := vktest.NewServer()
+			defer srv.Close()
+			calls := 0
+			srv.HandleFunc("users.get", func(params url.Values) (interface{}, error) {
+				calls++
+				if calls <= tt.captchas {
+					return nil, captcha
+				}
+				if params.Get("captcha_sid") != "sid" || params.Get("captcha_key") != "key-sid" {
+					t.Errorf("captcha params = %v", params)
+				}
+				return []vkapi.User{{ID: 1}}, nil
+			})
+
+			vk := srv.Client("token")
+			vk.CaptchaHandler = tt.handler
+			_, err := vk.UsersGetContext(context.Background(), &vkapi.UsersGetReq{})
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestValidationHandler(t *testing.T) {
+	srv := vktest.NewServer()
+	defer srv.Close()
+	validated := false
+	srv.HandleFunc("users.get", func(url.Values) (interface{}, error) {
+		if !validated {
+			return nil, vkapi.APIError{Code: int(vkapi.ErrValidationRequired), RedirectURI: "https://vk.com/validate"}
+		}
+		return []vkapi.User{{ID: 1}}, nil
+	})
+
+	vk := srv.Client("token")
+	vk.ValidationHandler = vkapi.ValidationHandlerFunc(func(ctx context.Context, redirectURI string) error {
+		if redirectURI != "https://vk.com/validate" {
+			t.Errorf("redirectURI = %q", redirectURI)
+		}
+		validated = true
+		return nil
+	})
+	users, err := vk.UsersGetContext(context.Background(), &vkapi.UsersGetReq{})
+	if err != nil || len(users) != 1 {
+		t.Errorf("UsersGet = %v, %v", users, err)
+	}
+}
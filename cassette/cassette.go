@@ -0,0 +1,247 @@
+// Package cassette records API traffic to disk and replays it in tests.
+//
+// In record mode real requests pass through and are saved with access
+// tokens scrubbed:
+//
+//	rec, _ := cassette.New("testdata/wall.json", cassette.Record)
+//	vk := vkapi.NewVkAPIWithClient(token, rec.Client())
+//	...
+//	rec.Save()
+//
+// In replay mode requests are answered from the file. API calls are
+// matched by method name and normalized params, other requests (long
+// poll, uploads) by URL. Idempotency keys (random_id, guid) are
+// ignored when matching.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+type Mode int
+
+const (
+	Replay Mode = iota
+	Record
+)
+
+// scrubbed are params never written to disk nor used for matching.
+var scrubbed = []string{"access_token"}
+
+// unmatched are params saved to disk but ignored when matching, such as
+// idempotency keys generated anew on every run.
+var unmatched = []string{"random_id", "guid"}
+
+// Interaction is a recorded request/response pair.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	// Key identifies the request during replay.
+	Key    string `json:"key"`
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Form   string `json:"form,omitempty"`
+}
+
+type Response struct {
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+}
+
+// Recorder is an http.RoundTripper recording or replaying interactions.
+type Recorder struct {
+	// Transport sends requests in record mode, http.DefaultTransport if nil.
+	Transport http.RoundTripper
+
+	path string
+	mode Mode
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// New returns a recorder for the cassette at path. In replay mode the
+// cassette is loaded from disk.
+func New(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode}
+	if mode == Record {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &r.interactions); err != nil {
+		return nil, fmt.Errorf("cassette %s: %w", path, err)
+	}
+	r.used = make([]bool, len(r.interactions))
+	return r, nil
+}
+
+// Client returns an HTTP client using the recorder as transport.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Save writes recorded interactions to disk.
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data, 0o644)
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.mode == Replay {
+		return r.replay(req, rec)
+	}
+	return r.record(req, rec)
+}
+
+func (r *Recorder) replay(req *http.Request, rec Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, in := range r.interactions {
+		if r.used[i] || in.Request.Key != rec.Key {
+			continue
+		}
+		r.used[i] = true
+		return in.Response.httpResponse(req), nil
+	}
+	return nil, fmt.Errorf("cassette %s: no interaction for %s", r.path, rec.Key)
+}
+
+func (r *Recorder) record(req *http.Request, rec Request) (*http.Response, error) {
+	t := r.Transport
+	if t == nil {
+		t = http.DefaultTransport
+	}
+
+	resp, err := t.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, Interaction{
+		Request: rec,
+		Response: Response{
+			StatusCode:  resp.StatusCode,
+			ContentType: resp.Header.Get("Content-Type"),
+			Body:        string(body),
+		},
+	})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// newRequest describes req with secrets scrubbed. The body of req is
+// restored so that it can still be sent.
+func newRequest(req *http.Request) (Request, error) {
+	u := *req.URL
+	q := scrub(u.Query())
+	u.RawQuery = q.Encode()
+
+	rec := Request{
+		Method: req.Method,
+		URL:    u.String(),
+	}
+
+	form := url.Values{}
+	if req.Body != nil && strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return rec, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		if form, err = url.ParseQuery(string(body)); err != nil {
+			return rec, err
+		}
+		form = scrub(form)
+		rec.Form = form.Encode()
+	}
+
+	if i := strings.Index(u.Path, "/method/"); i >= 0 {
+		rec.Key = "api " + u.Path[i+len("/method/"):] + "?" + matchKey(form)
+	} else {
+		rec.Key = req.Method + " " + u.Host + u.Path + "?" + matchKey(q)
+	}
+	return rec, nil
+}
+
+// matchKey encodes v without the unmatched params.
+func matchKey(v url.Values) string {
+	out := make(url.Values, len(v))
+	for k, vs := range v {
+		out[k] = vs
+	}
+	for _, k := range unmatched {
+		out.Del(k)
+	}
+	return out.Encode()
+}
+
+// scrub drops secrets and empty params. url.Values.Encode sorts keys,
+// which makes the result a normalized form of the params.
+func scrub(v url.Values) url.Values {
+	out := make(url.Values, len(v))
+	for k, vs := range v {
+		if len(vs) == 0 || (len(vs) == 1 && vs[0] == "") {
+			continue
+		}
+		out[k] = vs
+	}
+	for _, k := range scrubbed {
+		out.Del(k)
+	}
+	return out
+}
+
+func (r Response) httpResponse(req *http.Request) *http.Response {
+	h := http.Header{}
+	if r.ContentType != "" {
+		h.Set("Content-Type", r.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
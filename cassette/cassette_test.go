@@ -0,0 +1,82 @@
+package cassette_test
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+	"github.com/seilem/vk-golang-sdk/cassette"
+	"github.com/seilem/vk-golang-sdk/vktest"
+)
+
+func TestRecordReplay(t *testing.T) {
+	tests := []struct {
+		name     string
+		recorded url.Values
+		replayed url.Values
+		wantErr  bool
+	}{
+		{"same params", url.Values{"peer_id": {"1"}, "message": {"hi"}}, url.Values{"message": {"hi"}, "peer_id": {"1"}}, false},
+		{"new random_id", url.Values{"peer_id": {"1"}, "random_id": {"111"}}, url.Values{"peer_id": {"1"}, "random_id": {"222"}}, false},
+		{"new guid", url.Values{"peer_id": {"1"}, "guid": {"a"}}, url.Values{"peer_id": {"1"}, "guid": {"b"}}, false},
+		{"empty params ignored", url.Values{"peer_id": {"1"}}, url.Values{"peer_id": {"1"}, "message": {""}}, false},
+		{"other params", url.Values{"peer_id": {"1"}}, url.Values{"peer_id": {"2"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := vktest.NewServer()
+			defer srv.Close()
+			srv.Handle("messages.send", 42)
+			path := filepath.Join(t.TempDir(), "cassette.json")
+
+			rec, err := cassette.New(path, cassette.Record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec.Transport = srv.Server.Client().Transport
+			send(t, srv, rec, "secret", tt.recorded, false)
+			if err := rec.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(data), "secret") {
+				t.Errorf("cassette contains the access token: %s", data)
+			}
+
+			play, err := cassette.New(path, cassette.Replay)
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv.Close()
+			send(t, srv, play, "other", tt.replayed, tt.wantErr)
+		})
+	}
+}
+
+func send(t *testing.T, srv *vktest.Server, rec *cassette.Recorder, token string, params url.Values, wantErr bool) {
+	t.Helper()
+	vk := vkapi.NewVkAPIWithClient(token, rec.Client())
+	vk.BaseURL = srv.URL + "/method/"
+
+	resp, err := vk.MakeRequestContext(context.Background(), "messages.send", params)
+	if wantErr {
+		if err == nil {
+			t.Error("request matched an interaction recorded with other params")
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Response) != "42" {
+		t.Errorf("response = %s, want 42", resp.Response)
+	}
+}
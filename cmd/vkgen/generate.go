@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const header = "// Code generated by vkgen. DO NOT EDIT.\n\n"
+
+type generator struct {
+	s *Schema
+
+	// responseNames maps response definitions to Go type names,
+	// which must not clash with object names.
+	responseNames map[string]string
+}
+
+// generate returns generated file contents by file name.
+func generate(s *Schema, pkg string) (map[string][]byte, error) {
+	g := &generator{s: s, responseNames: responseNames(s)}
+
+	files := map[string]string{
+		"objects.go":   g.objects(),
+		"responses.go": g.responses(),
+		"methods.go":   g.methods(),
+	}
+
+	out := make(map[string][]byte, len(files))
+	for name, body := range files {
+		src := g.file(pkg, name, body)
+		formatted, err := format.Source(src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		out[name] = formatted
+	}
+	return out, nil
+}
+
+func (g *generator) file(pkg, name, body string) []byte {
+	var imports []string
+	if strings.Contains(body, "context.") {
+		imports = append(imports, `"context"`)
+	}
+	if strings.Contains(body, "json.") {
+		imports = append(imports, `"encoding/json"`)
+	}
+	if strings.Contains(body, "vkapi.") {
+		imports = append(imports, "", `vkapi "github.com/seilem/vk-golang-sdk"`)
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(header)
+	fmt.Fprintf(buf, "package %s\n\n", pkg)
+	if len(imports) > 0 {
+		fmt.Fprintf(buf, "import (\n%s\n)\n\n", strings.Join(imports, "\n"))
+	}
+	buf.WriteString(body)
+	return buf.Bytes()
+}
+
+func (g *generator) objects() string {
+	buf := &bytes.Buffer{}
+	for _, name := range sortedKeys(g.s.Objects) {
+		t := g.s.Objects[name]
+		writeDoc(buf, t.Description)
+		if props, required := g.properties(t); props != nil {
+			fmt.Fprintf(buf, "type %s %s\n\n", goName(name), g.structType(props, required))
+			continue
+		}
+		fmt.Fprintf(buf, "type %s %s\n\n", goName(name), g.goType(t))
+	}
+	return buf.String()
+}
+
+// responseNames names response types after their definitions, with
+// a Response suffix where an object has the same name, e.g. the
+// base_ok_response response becomes BaseOkResponseResponse next to
+// the BaseOkResponse object.
+func responseNames(s *Schema) map[string]string {
+	seen := make(map[string]bool, len(s.Objects)+len(s.Responses))
+	for name := range s.Objects {
+		seen[goName(name)] = true
+	}
+	names := make(map[string]string, len(s.Responses))
+	for _, name := range sortedKeys(s.Responses) {
+		n := goName(name)
+		if seen[n] {
+			n += "Response"
+		}
+		names[name] = uniqueName(n, seen)
+	}
+	return names
+}
+
+func (g *generator) responses() string {
+	buf := &bytes.Buffer{}
+	for _, name := range sortedKeys(g.s.Responses) {
+		t := g.s.Responses[name]
+		typ := "json.RawMessage"
+		if r, ok := t.Properties["response"]; ok {
+			typ = g.goType(r)
+		}
+		fmt.Fprintf(buf, "type %s %s\n\n", g.responseNames[name], typ)
+	}
+	return buf.String()
+}
+
+func (g *generator) methods() string {
+	buf := &bytes.Buffer{}
+	buf.WriteString(`// Client provides typed wrappers of all API methods.
+type Client struct {
+	*vkapi.VkAPI
+}
+
+// New returns a Client sending requests through vk.
+func New(vk *vkapi.VkAPI) *Client {
+	return &Client{VkAPI: vk}
+}
+
+`)
+
+	methods := append([]*Method(nil), g.s.Methods...)
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].Name < methods[j].Name
+	})
+
+	for _, m := range methods {
+		name := goName(strings.ReplaceAll(m.Name, ".", "_"))
+		params := name + "Params"
+
+		fmt.Fprintf(buf, "// %s are params of %s.\ntype %s struct {\n", params, m.Name, params)
+		// Name is taken by the method of the params type.
+		seen := map[string]bool{"Name": true}
+		for _, p := range m.Parameters {
+			field := goName(p.Name)
+			if field == "Name" {
+				field = "NameParam"
+			}
+			field = uniqueName(field, seen)
+			tag := p.Name
+			if !p.Required {
+				tag += ",omitempty"
+			}
+			writeFieldDoc(buf, p.Description)
+			fmt.Fprintf(buf, "\t%s %s `vk:\"%s\"`\n", field, paramType(&p.Type), tag)
+		}
+		buf.WriteString("}\n\n")
+
+		fmt.Fprintf(buf, "func (%s) Name() string {\n\treturn %q\n}\n\n", params, m.Name)
+
+		resp := "json.RawMessage"
+		if r, ok := m.Responses["response"]; ok && r.Ref != "" {
+			resp = g.refType(r.Ref)
+		}
+
+		if m.Description != "" {
+			writeDoc(buf, name+" "+lowerFirst(m.Description))
+			buf.WriteString("//\n")
+		}
+		fmt.Fprintf(buf, "// See https://vk.com/dev/%s\n", m.Name)
+		fmt.Fprintf(buf, "func (c *Client) %s(ctx context.Context, r *%s) (%s, error) {\n", name, params, resp)
+		fmt.Fprintf(buf, "\treturn vkapi.Call[%s](ctx, c.VkAPI, r)\n}\n\n", resp)
+	}
+	return buf.String()
+}
+
+// properties returns object properties of t and the set of required
+// ones, merging allOf parts. It returns nil if t is not an object.
+func (g *generator) properties(t *Type) (map[string]*Type, map[string]bool) {
+	if len(t.AllOf) == 0 {
+		if t.Kind() == "object" && len(t.Properties) > 0 {
+			return t.Properties, t.RequiredFields()
+		}
+		return nil, nil
+	}
+
+	props := map[string]*Type{}
+	required := map[string]bool{}
+	for _, part := range t.AllOf {
+		if part.Ref != "" {
+			if def := g.def(part.Ref); def != nil && def != t {
+				part = def
+			}
+		}
+		p, r := g.properties(part)
+		for k, v := range p {
+			props[k] = v
+		}
+		for k := range r {
+			required[k] = true
+		}
+	}
+	return props, required
+}
+
+func (g *generator) structType(props map[string]*Type, required map[string]bool) string {
+	buf := &bytes.Buffer{}
+	buf.WriteString("struct {\n")
+	seen := map[string]bool{}
+	for _, name := range sortedKeys(props) {
+		p := props[name]
+		tag := name
+		if !required[name] {
+			tag += ",omitempty"
+		}
+		writeFieldDoc(buf, p.Description)
+		fmt.Fprintf(buf, "\t%s %s `json:\"%s\"`\n", uniqueName(goName(name), seen), g.goType(p), tag)
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
+
+func (g *generator) goType(t *Type) string {
+	if t == nil {
+		return "json.RawMessage"
+	}
+	if t.Ref != "" {
+		return g.refType(t.Ref)
+	}
+	if props, required := g.properties(t); props != nil {
+		return g.structType(props, required)
+	}
+
+	switch t.Kind() {
+	case "integer":
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "string":
+		return "string"
+	case "array":
+		return "[]" + g.goType(t.Items)
+	}
+	return "json.RawMessage"
+}
+
+// refType returns the Go type a $ref points to. Refs without a file
+// name point to objects, as the schema only uses them there.
+func (g *generator) refType(ref string) string {
+	if isResponseRef(ref) {
+		if n, ok := g.responseNames[refName(ref)]; ok {
+			return n
+		}
+	}
+	return goName(refName(ref))
+}
+
+// def returns the definition a $ref points to, or nil.
+func (g *generator) def(ref string) *Type {
+	if isResponseRef(ref) {
+		return g.s.Responses[refName(ref)]
+	}
+	return g.s.Objects[refName(ref)]
+}
+
+// paramType maps a method param to a type EncodeValues can encode.
+func paramType(t *Type) string {
+	switch t.Kind() {
+	case "integer":
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		if t.Items != nil {
+			if s := paramType(t.Items); !strings.HasPrefix(s, "[]") {
+				return "[]" + s
+			}
+		}
+	}
+	return "string"
+}
+
+var initialisms = map[string]string{
+	"id":   "ID",
+	"ids":  "IDs",
+	"url":  "URL",
+	"uri":  "URI",
+	"api":  "API",
+	"http": "HTTP",
+	"json": "JSON",
+	"sid":  "SID",
+	"ip":   "IP",
+	"lp":   "LP",
+	"sms":  "SMS",
+}
+
+// goName converts snake_case to an exported Go identifier.
+func goName(s string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == '_' || r == '.' || r == '-' || r == ' '
+	}) {
+		if v, ok := initialisms[strings.ToLower(part)]; ok {
+			b.WriteString(v)
+			continue
+		}
+		r := []rune(part)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
+
+func uniqueName(name string, seen map[string]bool) string {
+	n := name
+	for i := 2; seen[n]; i++ {
+		n = fmt.Sprintf("%s%d", name, i)
+	}
+	seen[n] = true
+	return n
+}
+
+func lowerFirst(s string) string {
+	r := []rune(s)
+	if len(r) > 1 && unicode.IsUpper(r[0]) && !unicode.IsUpper(r[1]) {
+		r[0] = unicode.ToLower(r[0])
+	}
+	return string(r)
+}
+
+func writeDoc(buf *bytes.Buffer, doc string) {
+	doc = strings.TrimSpace(doc)
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		fmt.Fprintf(buf, "// %s\n", strings.TrimSpace(line))
+	}
+}
+
+func writeFieldDoc(buf *bytes.Buffer, doc string) {
+	doc = strings.TrimSpace(doc)
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		fmt.Fprintf(buf, "\t// %s\n", strings.TrimSpace(line))
+	}
+}
+
+func sortedKeys(m map[string]*Type) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata/api")
+
+func TestGenerate(t *testing.T) {
+	s, err := loadSchema(filepath.Join("testdata", "schema"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := generate(s, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	var parsed []*ast.File
+	for name, src := range files {
+		golden := filepath.Join("testdata", "api", name)
+		if *update {
+			if err := os.WriteFile(golden, src, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(src, want) {
+			t.Errorf("%s differs from %s; if the change is intended, run go test -update:\n%s", name, golden, src)
+		}
+
+		f, err := parser.ParseFile(fset, name, src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed = append(parsed, f)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "gc", exportData)}
+	if _, err := conf.Check("api", fset, parsed, nil); err != nil {
+		t.Errorf("generated code does not compile: %v", err)
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"user_ids", "UserIDs"},
+		{"users.get", "UsersGet"},
+		{"owner_id", "OwnerID"},
+		{"http_url", "HTTPURL"},
+		{"2fa_required", "X2faRequired"},
+		{"", "X"},
+	}
+	for _, tt := range tests {
+		if got := goName(tt.in); got != tt.want {
+			t.Errorf("goName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// exportData opens the export data of the package path, building it with
+// the go command if needed. It is much faster than type checking the
+// imported packages from source.
+func exportData(path string) (io.ReadCloser, error) {
+	out, err := exec.Command("go", "list", "-export", "-f", "{{.Export}}", path).Output()
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(strings.TrimSpace(string(out)))
+}
@@ -0,0 +1,51 @@
+// Command vkgen generates request, response and object types together
+// with typed wrappers from a local copy of the VK API JSON schema
+// (https://github.com/VKCOM/vk-api-schema).
+//
+// Usage:
+//
+//	vkgen -schema ./vk-api-schema -out ./api -pkg api
+//
+// The generated package wraps *vkapi.VkAPI in a Client type, so it
+// lives next to the hand-written methods without name clashes:
+//
+//	c := api.New(vkapi.NewVkAPI(token))
+//	resp, err := c.UsersGet(ctx, &api.UsersGetParams{UserIDs: []string{"1"}})
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	schemaDir := flag.String("schema", ".", "directory with methods.json, objects.json and responses.json")
+	out := flag.String("out", "api", "output directory")
+	pkg := flag.String("pkg", "", "package name, the base name of -out by default")
+	flag.Parse()
+
+	if *pkg == "" {
+		*pkg = filepath.Base(*out)
+	}
+
+	s, err := loadSchema(*schemaDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	files, err := generate(s, *pkg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(*out, name), src, 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
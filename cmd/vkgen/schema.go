@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Type is a JSON schema node as used by the VK API schema.
+type Type struct {
+	Ref         string           `json:"$ref"`
+	Type        json.RawMessage  `json:"type"`
+	Description string           `json:"description"`
+	Properties  map[string]*Type `json:"properties"`
+	Required    json.RawMessage  `json:"required"`
+	Items       *Type            `json:"items"`
+	AllOf       []*Type          `json:"allOf"`
+	OneOf       []*Type          `json:"oneOf"`
+	Enum        []interface{}    `json:"enum"`
+}
+
+// Kind returns the JSON type of t, or "" if it is missing or ambiguous.
+func (t *Type) Kind() string {
+	var s string
+	if err := json.Unmarshal(t.Type, &s); err == nil {
+		return s
+	}
+	return ""
+}
+
+// RequiredFields returns property names listed as required.
+// Some schema versions use a boolean per property instead of a list.
+func (t *Type) RequiredFields() map[string]bool {
+	var names []string
+	_ = json.Unmarshal(t.Required, &names)
+
+	r := make(map[string]bool, len(names))
+	for _, n := range names {
+		r[n] = true
+	}
+	return r
+}
+
+type Param struct {
+	Type
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+}
+
+type Method struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Parameters  []*Param         `json:"parameters"`
+	Responses   map[string]*Type `json:"responses"`
+}
+
+type Schema struct {
+	Methods   []*Method
+	Objects   map[string]*Type
+	Responses map[string]*Type
+}
+
+func loadSchema(dir string) (*Schema, error) {
+	var methods struct {
+		Methods []*Method `json:"methods"`
+	}
+	var objects, responses struct {
+		Definitions map[string]*Type `json:"definitions"`
+	}
+
+	for name, v := range map[string]interface{}{
+		"methods.json":   &methods,
+		"objects.json":   &objects,
+		"responses.json": &responses,
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return &Schema{
+		Methods:   methods.Methods,
+		Objects:   objects.Definitions,
+		Responses: responses.Definitions,
+	}, nil
+}
+
+// refName returns the definition name of a $ref like
+// "objects.json#/definitions/users_user_full".
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// isResponseRef reports whether ref points into responses.json.
+func isResponseRef(ref string) bool {
+	return strings.HasPrefix(ref, "responses.json#")
+}
@@ -0,0 +1,73 @@
+// Code generated by vkgen. DO NOT EDIT.
+
+package api
+
+import (
+	"context"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+)
+
+// Client provides typed wrappers of all API methods.
+type Client struct {
+	*vkapi.VkAPI
+}
+
+// New returns a Client sending requests through vk.
+func New(vk *vkapi.VkAPI) *Client {
+	return &Client{VkAPI: vk}
+}
+
+// GroupsEditParams are params of groups.edit.
+type GroupsEditParams struct {
+	GroupID int `vk:"group_id"`
+	// Community title.
+	NameParam string `vk:"name,omitempty"`
+}
+
+func (GroupsEditParams) Name() string {
+	return "groups.edit"
+}
+
+// GroupsEdit edits a community.
+//
+// See https://vk.com/dev/groups.edit
+func (c *Client) GroupsEdit(ctx context.Context, r *GroupsEditParams) (BaseOkResponseResponse, error) {
+	return vkapi.Call[BaseOkResponseResponse](ctx, c.VkAPI, r)
+}
+
+// UsersGetParams are params of users.get.
+type UsersGetParams struct {
+	UserIDs []string `vk:"user_ids,omitempty"`
+	Fields  []string `vk:"fields,omitempty"`
+}
+
+func (UsersGetParams) Name() string {
+	return "users.get"
+}
+
+// UsersGet returns detailed information on users.
+//
+// See https://vk.com/dev/users.get
+func (c *Client) UsersGet(ctx context.Context, r *UsersGetParams) (UsersGetResponse, error) {
+	return vkapi.Call[UsersGetResponse](ctx, c.VkAPI, r)
+}
+
+// UsersSearchParams are params of users.search.
+type UsersSearchParams struct {
+	// Search query string.
+	Q        string `vk:"q,omitempty"`
+	Count    int    `vk:"count,omitempty"`
+	HasPhoto bool   `vk:"has_photo,omitempty"`
+}
+
+func (UsersSearchParams) Name() string {
+	return "users.search"
+}
+
+// UsersSearch returns a list of users matching the search criteria.
+//
+// See https://vk.com/dev/users.search
+func (c *Client) UsersSearch(ctx context.Context, r *UsersSearchParams) (UsersSearchResponse, error) {
+	return vkapi.Call[UsersSearchResponse](ctx, c.VkAPI, r)
+}
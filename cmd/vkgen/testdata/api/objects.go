@@ -0,0 +1,26 @@
+// Code generated by vkgen. DO NOT EDIT.
+
+package api
+
+type BaseBoolInt int
+
+type BaseOkResponse int
+
+type UsersUserFull struct {
+	Counters struct {
+		Friends int `json:"friends,omitempty"`
+	} `json:"counters,omitempty"`
+	Deactivated string `json:"deactivated,omitempty"`
+	FirstName   string `json:"first_name,omitempty"`
+	// User ID
+	ID       int         `json:"id"`
+	IsClosed BaseBoolInt `json:"is_closed,omitempty"`
+	Tags     []string    `json:"tags,omitempty"`
+}
+
+type UsersUserMin struct {
+	Deactivated string `json:"deactivated,omitempty"`
+	FirstName   string `json:"first_name,omitempty"`
+	// User ID
+	ID int `json:"id"`
+}
@@ -0,0 +1,12 @@
+// Code generated by vkgen. DO NOT EDIT.
+
+package api
+
+type BaseOkResponseResponse BaseOkResponse
+
+type UsersGetResponse []UsersUserFull
+
+type UsersSearchResponse struct {
+	Count int            `json:"count"`
+	Items []UsersUserMin `json:"items"`
+}
@@ -0,0 +1,132 @@
+package vkapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"sync"
+)
+
+// ErrDispatcherClosed is returned by Dispatch after Close.
+var ErrDispatcherClosed = errors.New("dispatcher is closed")
+
+// Dispatcher runs long poll event handlers on a fixed number of
+// workers. Events with the same key, e.g. of one conversation, go to
+// the same worker and are handled in order of arrival; events with
+// different keys are handled in parallel.
+type Dispatcher struct {
+	queues []chan func()
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewDispatcher starts workers goroutines, each with a queue of
+// queueSize events. Close it when done.
+func NewDispatcher(workers, queueSize int) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	d := &Dispatcher{queues: make([]chan func(), workers)}
+	for i := range d.queues {
+		q := make(chan func(), queueSize)
+		d.queues[i] = q
+		d.wg.Add(1)
+		go d.work(q)
+	}
+	return d
+}
+
+func (d *Dispatcher) work(q chan func()) {
+	defer d.wg.Done()
+	for f := range q {
+		f()
+	}
+}
+
+// Dispatch queues f on the worker of key. If the queue is full it
+// blocks until there is room or ctx is done, which slows down the
+// long poll instead of buffering events without limit.
+func (d *Dispatcher) Dispatch(ctx context.Context, key string, f func()) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return ErrDispatcherClosed
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	q := d.queues[h.Sum32()%uint32(len(d.queues))]
+
+	select {
+	case q <- f:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting events and waits for the queued ones.
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	for _, q := range d.queues {
+		close(q)
+	}
+	d.mu.Unlock()
+	d.wg.Wait()
+}
+
+// dispatchEvent runs f on vk.Dispatcher, or right away if it is nil.
+func (vk *VkAPI) dispatchEvent(ctx context.Context, key string, f func()) error {
+	if vk.Dispatcher == nil {
+		f()
+		return nil
+	}
+	return vk.Dispatcher.Dispatch(ctx, key, f)
+}
+
+// orderKey returns the conversation of a message event or the owner
+// of the object otherwise. Events without either are ordered by type.
+func (u *GroupLPUpdates) orderKey() string {
+	var o struct {
+		Message *struct {
+			PeerID int64 `json:"peer_id"`
+		} `json:"message"`
+		PeerID       int64 `json:"peer_id"`
+		PostOwnerID  int64 `json:"post_owner_id"`
+		PhotoOwnerID int64 `json:"photo_owner_id"`
+		VideoOwnerID int64 `json:"video_owner_id"`
+		TopicOwnerID int64 `json:"topic_owner_id"`
+		OwnerID      int64 `json:"owner_id"`
+		UserID       int64 `json:"user_id"`
+		FromID       int64 `json:"from_id"`
+	}
+	if err := json.Unmarshal(u.Object, &o); err != nil {
+		return u.Type
+	}
+	if o.Message != nil && o.Message.PeerID != 0 {
+		return strconv.FormatInt(o.Message.PeerID, 10)
+	}
+
+	for _, id := range []int64{
+		o.PeerID, o.PostOwnerID, o.PhotoOwnerID, o.VideoOwnerID,
+		o.TopicOwnerID, o.OwnerID, o.UserID, o.FromID,
+	} {
+		if id != 0 {
+			return strconv.FormatInt(id, 10)
+		}
+	}
+	return u.Type
+}
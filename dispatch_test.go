@@ -0,0 +1,191 @@
+package vkapi_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+	"github.com/seilem/vk-golang-sdk/vktest"
+)
+
+func TestDispatcherOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		workers   int
+		queueSize int
+	}{
+		{"one worker", 1, 0},
+		{"workers", 4, 0},
+		{"queued", 4, 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := vkapi.NewDispatcher(tt.workers, tt.queueSize)
+
+			var (
+				mu  sync.Mutex
+				got = map[string][]int{}
+			)
+			for i := 0; i < 100; i++ {
+				key, i := strconv.Itoa(i%5), i
+				err := d.Dispatch(context.Background(), key, func() {
+					mu.Lock()
+					got[key] = append(got[key], i)
+					mu.Unlock()
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			d.Close()
+
+			for key, seq := range got {
+				if len(seq) != 20 {
+					t.Errorf("key %s: handled %d events, want 20", key, len(seq))
+				}
+				for j := 1; j < len(seq); j++ {
+					if seq[j] < seq[j-1] {
+						t.Errorf("key %s: handled out of order: %v", key, seq)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDispatcherBackpressure(t *testing.T) {
+	d := vkapi.NewDispatcher(1, 1)
+	release := make(chan struct{})
+	defer func() {
+		close(release)
+		d.Close()
+	}()
+
+	block := func() { <-release }
+	// One event is running and one is queued, so the worker is full.
+	for i := 0; i < 2; i++ {
+		if err := d.Dispatch(context.Background(), "a", block); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := d.Dispatch(ctx, "a", block); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Dispatch = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestDispatcherClose(t *testing.T) {
+	d := vkapi.NewDispatcher(2, 4)
+	var (
+		mu      sync.Mutex
+		handled int
+	)
+	for i := 0; i < 4; i++ {
+		d.Dispatch(context.Background(), strconv.Itoa(i), func() {
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			handled++
+			mu.Unlock()
+		})
+	}
+	d.Close()
+	d.Close()
+
+	if handled != 4 {
+		t.Errorf("Close returned after %d of 4 queued events", handled)
+	}
+	if err := d.Dispatch(context.Background(), "a", func() {}); err != vkapi.ErrDispatcherClosed {
+		t.Errorf("Dispatch after Close = %v, want %v", err, vkapi.ErrDispatcherClosed)
+	}
+}
+
+func TestLongPollDispatcherClosed(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(ctx context.Context, srv *vktest.Server, vk *vkapi.VkAPI) error
+	}{
+		{
+			name: "group",
+			run: func(ctx context.Context, srv *vktest.Server, vk *vkapi.VkAPI) error {
+				srv.GroupLP.Push(groupUpdate(1))
+				return vkapi.NewGroupLongPoll(vk, 1).Run(ctx)
+			},
+		},
+		{
+			name: "user",
+			run: func(ctx context.Context, srv *vktest.Server, vk *vkapi.VkAPI) error {
+				srv.UserLP.Push([]interface{}{vkapi.MsgLPCodeNewMessage, 1, 3, 2000000001, 1600000000, "hi", "", map[string]string{}})
+				return vk.MsgLPServContext(ctx, 1, 2)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := vktest.NewServer()
+			defer srv.Close()
+
+			vk := srv.Client("token")
+			vk.Dispatcher = vkapi.NewDispatcher(1, 0)
+			vk.Dispatcher.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if err := tt.run(ctx, srv, vk); err != vkapi.ErrDispatcherClosed {
+				t.Errorf("long poll returned %v, want %v", err, vkapi.ErrDispatcherClosed)
+			}
+		})
+	}
+}
+
+func TestLongPollDispatchOrder(t *testing.T) {
+	srv := vktest.NewServer()
+	defer srv.Close()
+	srv.GroupLP.Wait = 10 * time.Millisecond
+
+	vk := srv.Client("token")
+	vk.Dispatcher = vkapi.NewDispatcher(4, 0)
+	defer vk.Dispatcher.Close()
+
+	var (
+		mu  sync.Mutex
+		got = map[int64][]int{}
+	)
+	vk.OnMessageNew(func(_ context.Context, m *vkapi.NewMessage) {
+		mu.Lock()
+		got[m.Message.PeerID] = append(got[m.Message.PeerID], int(m.Message.ID))
+		mu.Unlock()
+	})
+
+	var updates []interface{}
+	for i := 0; i < 30; i++ {
+		updates = append(updates, messageUpdate(int64(i%3+1), i))
+	}
+	srv.GroupLP.Push(updates...)
+	runUntil(t, vkapi.NewGroupLongPoll(vk, 1), func() bool { return srv.GroupLP.Checks() >= 2 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	if n := len(got[1]) + len(got[2]) + len(got[3]); n != len(updates) {
+		t.Errorf("handled %d of %d messages", n, len(updates))
+	}
+	for peer, ids := range got {
+		for j := 1; j < len(ids); j++ {
+			if ids[j] < ids[j-1] {
+				t.Errorf("peer %d: messages handled out of order: %v", peer, ids)
+				break
+			}
+		}
+	}
+}
+
+func messageUpdate(peerID int64, id int) vkapi.GroupLPUpdates {
+	obj := `{"message":{"id":` + strconv.Itoa(id) + `,"peer_id":` + strconv.FormatInt(peerID, 10) + `}}`
+	return vkapi.GroupLPUpdates{Type: vkapi.EventMessageNew, Object: []byte(obj), GroupID: 1}
+}
@@ -0,0 +1,208 @@
+package vkapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Valuer is implemented by requests that encode their params by hand.
+// Requests without it are encoded with EncodeValues.
+type Valuer interface {
+	Values() url.Values
+}
+
+// ValuesEncoder is implemented by requests that encode their params by
+// hand and may fail doing so, e.g. when marshaling JSON. It takes
+// precedence over Valuer.
+type ValuesEncoder interface {
+	EncodeValues() (url.Values, error)
+}
+
+// EncodeValues encodes fields of the struct v tagged with `vk` into
+// request params. The tag holds the param name followed by options:
+//
+//	PeerID   int64     `vk:"peer_id,omitempty"`
+//	Keyboard *Keyboard `vk:"keyboard,json,omitempty"`
+//
+// omitempty skips zero values and empty slices, json forces JSON
+// encoding. Bools are encoded as 0/1, slices as comma separated lists,
+// time.Time as a Unix timestamp; structs and maps are encoded as JSON.
+// Untagged embedded structs are flattened.
+func EncodeValues(v interface{}) (url.Values, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return url.Values{}, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("vkapi: cannot encode %s as params", rv.Type())
+	}
+
+	values := url.Values{}
+	if err := encodeStruct(values, rv); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// methodValues returns params of m.
+func methodValues(m Method) (url.Values, error) {
+	var (
+		values url.Values
+		err    error
+	)
+	switch v := m.(type) {
+	case ValuesEncoder:
+		values, err = v.EncodeValues()
+	case Valuer:
+		values = v.Values()
+	default:
+		values, err = EncodeValues(m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", m.Name(), err)
+	}
+	return values, nil
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func encodeStruct(values url.Values, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		tag, tagged := f.Tag.Lookup("vk")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+
+		fv := rv.Field(i)
+		if !tagged {
+			if f.Anonymous {
+				for fv.Kind() == reflect.Ptr {
+					if fv.IsNil() {
+						break
+					}
+					fv = fv.Elem()
+				}
+				if fv.Kind() == reflect.Struct {
+					if err := encodeStruct(values, fv); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+
+		name, opts := parseTag(tag)
+		if opts.omitempty && isEmpty(fv) {
+			continue
+		}
+
+		s, err := encodeValue(fv, opts.json)
+		if err != nil {
+			return fmt.Errorf("param %s: %w", name, err)
+		}
+		values.Set(name, s)
+	}
+	return nil
+}
+
+type tagOptions struct {
+	omitempty bool
+	json      bool
+}
+
+func parseTag(tag string) (string, tagOptions) {
+	parts := strings.Split(tag, ",")
+	var opts tagOptions
+	for _, o := range parts[1:] {
+		switch o {
+		case "omitempty":
+			opts.omitempty = true
+		case "json":
+			opts.json = true
+		}
+	}
+	return parts[0], opts
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).IsZero()
+	}
+	return v.IsZero()
+}
+
+func encodeValue(v reflect.Value, asJSON bool) (string, error) {
+	if asJSON {
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return "", nil
+		}
+		return strconv.FormatInt(t.Unix(), 10), nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		data, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(data), err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return "1", nil
+		}
+		return "0", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	case reflect.Slice, reflect.Array:
+		items := make([]string, v.Len())
+		for i := range items {
+			s, err := encodeValue(v.Index(i), false)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	}
+	return encodeValue(v, true)
+}
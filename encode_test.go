@@ -0,0 +1,147 @@
+package vkapi
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+type encodeEmbedded struct {
+	Offset int `vk:"offset,omitempty"`
+}
+
+type encodeReq struct {
+	encodeEmbedded
+	ID       int64             `vk:"id"`
+	Name     string            `vk:"name,omitempty"`
+	Flag     bool              `vk:"flag"`
+	IDs      []int64           `vk:"ids,omitempty"`
+	Date     time.Time         `vk:"date,omitempty"`
+	Ratio    float64           `vk:"ratio,omitempty"`
+	Ptr      *int              `vk:"ptr,omitempty"`
+	Keyboard *Keyboard         `vk:"keyboard,json,omitempty"`
+	Extra    map[string]string `vk:"extra,omitempty"`
+	Skipped  string            `vk:"-"`
+	Untagged string
+}
+
+func TestEncodeValues(t *testing.T) {
+	one := 1
+	tests := []struct {
+		name    string
+		v       interface{}
+		want    url.Values
+		wantErr bool
+	}{
+		{"zero", encodeReq{}, url.Values{"id": {"0"}, "flag": {"0"}}, false},
+		{"nil pointer", (*encodeReq)(nil), url.Values{}, false},
+		{
+			name: "all kinds",
+			v: &encodeReq{
+				encodeEmbedded: encodeEmbedded{Offset: 10},
+				ID:             -5,
+				Name:           "a b",
+				Flag:           true,
+				IDs:            []int64{1, 2},
+				Date:           time.Unix(1600000000, 0),
+				Ratio:          0.5,
+				Ptr:            &one,
+				Keyboard:       &Keyboard{OneTime: true},
+				Extra:          map[string]string{"k": "v"},
+				Skipped:        "x",
+				Untagged:       "x",
+			},
+			want: url.Values{
+				"offset":   {"10"},
+				"id":       {"-5"},
+				"name":     {"a b"},
+				"flag":     {"1"},
+				"ids":      {"1,2"},
+				"date":     {"1600000000"},
+				"ratio":    {"0.5"},
+				"ptr":      {"1"},
+				"keyboard": {`{"one_time":true,"buttons":null,"inline":false}`},
+				"extra":    {`{"k":"v"}`},
+			},
+		},
+		{"not a struct", 42, nil, true},
+		{"json error", struct {
+			C chan int `vk:"c,json"`
+		}{}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EncodeValues(tt.v)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Encode() != tt.want.Encode() {
+				t.Errorf("EncodeValues = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type handEncodedReq struct{ id string }
+
+func (handEncodedReq) Name() string { return "users.get" }
+
+func (r handEncodedReq) Values() url.Values { return url.Values{"user_ids": {r.id}} }
+
+// taggedValuerReq has `vk` tags but encodes its params by hand.
+type taggedValuerReq struct {
+	ID string `vk:"id"`
+}
+
+func (taggedValuerReq) Name() string { return "users.get" }
+
+func (r taggedValuerReq) Values() url.Values { return url.Values{"user_ids": {r.ID}} }
+
+func TestMethodValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       Method
+		want    url.Values
+		wantErr bool
+	}{
+		{"valuer", handEncodedReq{"1"}, url.Values{"user_ids": {"1"}}, false},
+		{"tagged", &UsersGetReq{UserIDs: []string{"1", "2"}}, url.Values{"user_ids": {"1,2"}}, false},
+		{"tagged valuer", taggedValuerReq{"1"}, url.Values{"user_ids": {"1"}}, false},
+		{"encoder error", &MsgReq{Keyboard: &Keyboard{Buttons: [][]Button{{{Color: make(chan int)}}}}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := methodValues(tt.m)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Encode() != tt.want.Encode() {
+				t.Errorf("methodValues = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeprecatedValues(t *testing.T) {
+	tests := []struct {
+		name string
+		m    interface {
+			Method
+			Valuer
+		}
+	}{
+		{"pointer receiver", &MsgReq{PeerID: 1, Message: "hi", Keyboard: &Keyboard{Inline: true}}},
+		{"value receiver", WallPostReq{OwnerID: -1, Message: "post"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := EncodeValues(tt.m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.m.Values(); got.Encode() != want.Encode() {
+				t.Errorf("Values = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package vkapi
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// ErrorCode is a documented API error code. Codes are usable as targets
+// of errors.Is:
+//
+//	if errors.Is(err, vkapi.ErrAuthFailed) { ... }
+//
+// See https://vk.com/dev/errors
+type ErrorCode int
+
+const (
+	ErrUnknown               ErrorCode = 1
+	ErrAppDisabled           ErrorCode = 2
+	ErrUnknownMethod         ErrorCode = 3
+	ErrInvalidSignature      ErrorCode = 4
+	ErrAuthFailed            ErrorCode = 5
+	ErrTooManyRequests       ErrorCode = 6
+	ErrPermissionDenied      ErrorCode = 7
+	ErrInvalidRequest        ErrorCode = 8
+	ErrFloodControl          ErrorCode = 9
+	ErrInternalServer        ErrorCode = 10
+	ErrTestMode              ErrorCode = 11
+	ErrCaptchaNeeded         ErrorCode = 14
+	ErrAccessDenied          ErrorCode = 15
+	ErrHTTPSRequired         ErrorCode = 16
+	ErrValidationRequired    ErrorCode = 17
+	ErrUserDeleted           ErrorCode = 18
+	ErrStandaloneOnly        ErrorCode = 20
+	ErrMethodDisabled        ErrorCode = 23
+	ErrConfirmationRequired  ErrorCode = 24
+	ErrGroupAuthFailed       ErrorCode = 27
+	ErrAppAuthFailed         ErrorCode = 28
+	ErrRateLimit             ErrorCode = 29
+	ErrPrivateProfile        ErrorCode = 30
+	ErrInvalidParam          ErrorCode = 100
+	ErrInvalidAppID          ErrorCode = 101
+	ErrInvalidUserID         ErrorCode = 113
+	ErrInvalidTimestamp      ErrorCode = 150
+	ErrAlbumAccessDenied     ErrorCode = 200
+	ErrAudioAccessDenied     ErrorCode = 201
+	ErrGroupAccessDenied     ErrorCode = 203
+	ErrAlbumFull             ErrorCode = 300
+	ErrVotesPermission       ErrorCode = 500
+	ErrAdsPermission         ErrorCode = 600
+	ErrMessagesBlacklist     ErrorCode = 900
+	ErrMessagesDenySend      ErrorCode = 901
+	ErrMessagesPrivacy       ErrorCode = 902
+	ErrMessagesKeyboard      ErrorCode = 911
+	ErrMessagesChatBot       ErrorCode = 912
+	ErrMessagesTooManyFwd    ErrorCode = 913
+	ErrMessagesTooLong       ErrorCode = 914
+	ErrMessagesChatAccess    ErrorCode = 917
+	ErrMessagesCantForward   ErrorCode = 921
+	ErrMessagesNotChatAdmin  ErrorCode = 925
+	ErrMessagesUserNotInChat ErrorCode = 935
+	ErrMessagesChatDisabled  ErrorCode = 945
+)
+
+func (c ErrorCode) Error() string {
+	return "api error " + strconv.Itoa(int(c))
+}
+
+// RequestParam is a request parameter echoed back by the API in an error.
+type RequestParam struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// APIError is an error returned by the API. Use errors.As with an
+// APIError target to inspect it.
+type APIError struct {
+	Code          int            `json:"error_code"`
+	Msg           string         `json:"error_msg"`
+	Method        string         `json:"method"`
+	RequestParams []RequestParam `json:"request_params"`
+	// CaptchaSID and CaptchaImg are set for ErrCaptchaNeeded.
+	CaptchaSID string `json:"captcha_sid"`
+	CaptchaImg string `json:"captcha_img"`
+	// RedirectURI is set for ErrValidationRequired.
+	RedirectURI string `json:"redirect_uri"`
+	// ConfirmationText is set for ErrConfirmationRequired.
+	ConfirmationText string `json:"confirmation_text"`
+	Raw              json.RawMessage
+}
+
+func (e APIError) Error() string {
+	return e.Msg
+}
+
+// Is reports whether target is the ErrorCode of e.
+func (e APIError) Is(target error) bool {
+	c, ok := target.(ErrorCode)
+	return ok && int(c) == e.Code
+}
@@ -0,0 +1,81 @@
+package vkapi_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+	"github.com/seilem/vk-golang-sdk/vktest"
+)
+
+func TestAPIErrorIs(t *testing.T) {
+	err := vkapi.APIError{Code: int(vkapi.ErrAccessDenied), Msg: "Access denied"}
+
+	tests := []struct {
+		name   string
+		err    error
+		target error
+		want   bool
+	}{
+		{"same code", err, vkapi.ErrAccessDenied, true},
+		{"other code", err, vkapi.ErrAuthFailed, false},
+		{"wrapped", fmt.Errorf("users.get: %w", err), vkapi.ErrAccessDenied, true},
+		{"not an error code", err, errors.New("Access denied"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, tt.target); got != tt.want {
+				t.Errorf("errors.Is = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIErrorDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want vkapi.APIError
+	}{
+		{
+			name: "error code",
+			err:  vkapi.ErrAccessDenied,
+			want: vkapi.APIError{Code: 15, Msg: vkapi.ErrAccessDenied.Error()},
+		},
+		{
+			name: "captcha",
+			err:  vkapi.APIError{Code: 14, Msg: "Captcha needed", CaptchaSID: "sid", CaptchaImg: "https://vk.com/captcha.php?sid=sid"},
+			want: vkapi.APIError{Code: 14, Msg: "Captcha needed", CaptchaSID: "sid", CaptchaImg: "https://vk.com/captcha.php?sid=sid"},
+		},
+		{
+			name: "validation",
+			err:  vkapi.APIError{Code: 17, Msg: "Validation required", RedirectURI: "https://vk.com/login"},
+			want: vkapi.APIError{Code: 17, Msg: "Validation required", RedirectURI: "https://vk.com/login"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := vktest.NewServer()
+			defer srv.Close()
+			srv.HandleError("users.get", tt.err)
+
+			_, err := srv.Client("token").UsersGetContext(context.Background(), &vkapi.UsersGetReq{})
+			var e vkapi.APIError
+			if !errors.As(err, &e) {
+				t.Fatalf("err = %#v, want APIError", err)
+			}
+			if e.Code != tt.want.Code || e.Msg != tt.want.Msg || e.CaptchaSID != tt.want.CaptchaSID ||
+				e.CaptchaImg != tt.want.CaptchaImg || e.RedirectURI != tt.want.RedirectURI {
+				t.Errorf("err = %+v, want %+v", e, tt.want)
+			}
+			if len(e.Raw) == 0 {
+				t.Error("Raw is empty")
+			}
+			if !errors.Is(err, vkapi.ErrorCode(tt.want.Code)) {
+				t.Errorf("errors.Is(err, %d) = false", tt.want.Code)
+			}
+		})
+	}
+}
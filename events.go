@@ -0,0 +1,407 @@
+package vkapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Community event types.
+//
+// See https://vk.com/dev/groups_events
+const (
+	EventMessageNew          = "message_new"
+	EventMessageReply        = "message_reply"
+	EventMessageEdit         = "message_edit"
+	EventMessageAllow        = "message_allow"
+	EventMessageDeny         = "message_deny"
+	EventMessageTypingState  = "message_typing_state"
+	EventMessageEvent        = "message_event"
+	EventPhotoNew            = "photo_new"
+	EventPhotoCommentNew     = "photo_comment_new"
+	EventPhotoCommentEdit    = "photo_comment_edit"
+	EventPhotoCommentRestore = "photo_comment_restore"
+	EventPhotoCommentDelete  = "photo_comment_delete"
+	EventVideoNew            = "video_new"
+	EventVideoCommentNew     = "video_comment_new"
+	EventVideoCommentEdit    = "video_comment_edit"
+	EventVideoCommentRestore = "video_comment_restore"
+	EventVideoCommentDelete  = "video_comment_delete"
+	EventWallPostNew         = "wall_post_new"
+	EventWallRepost          = "wall_repost"
+	EventWallReplyNew        = "wall_reply_new"
+	EventWallReplyEdit       = "wall_reply_edit"
+	EventWallReplyRestore    = "wall_reply_restore"
+	EventWallReplyDelete     = "wall_reply_delete"
+	EventBoardPostNew        = "board_post_new"
+	EventBoardPostEdit       = "board_post_edit"
+	EventBoardPostRestore    = "board_post_restore"
+	EventBoardPostDelete     = "board_post_delete"
+	EventGroupJoin           = "group_join"
+	EventGroupLeave          = "group_leave"
+	EventUserBlock           = "user_block"
+	EventUserUnblock         = "user_unblock"
+	EventPollVoteNew         = "poll_vote_new"
+	EventGroupOfficersEdit   = "group_officers_edit"
+	EventGroupChangeSettings = "group_change_settings"
+)
+
+type MessageAllow struct {
+	UserID int    `json:"user_id"`
+	Key    string `json:"key"`
+}
+
+type MessageDeny struct {
+	UserID int `json:"user_id"`
+}
+
+type MessageTypingState struct {
+	State  string `json:"state"`
+	FromID int    `json:"from_id"`
+	ToID   int    `json:"to_id"`
+}
+
+type MessageEvent struct {
+	UserID                int             `json:"user_id"`
+	PeerID                int64           `json:"peer_id"`
+	EventID               string          `json:"event_id"`
+	Payload               json.RawMessage `json:"payload"`
+	ConversationMessageID int             `json:"conversation_message_id"`
+}
+
+type PhotoComment struct {
+	Comment
+	PhotoID      int `json:"photo_id"`
+	PhotoOwnerID int `json:"photo_owner_id"`
+}
+
+type PhotoCommentDelete struct {
+	OwnerID   int `json:"owner_id"`
+	ID        int `json:"id"`
+	UserID    int `json:"user_id"`
+	DeleterID int `json:"deleter_id"`
+	PhotoID   int `json:"photo_id"`
+}
+
+type VideoComment struct {
+	Comment
+	VideoID      int `json:"video_id"`
+	VideoOwnerID int `json:"video_owner_id"`
+}
+
+type VideoCommentDelete struct {
+	OwnerID   int `json:"owner_id"`
+	ID        int `json:"id"`
+	UserID    int `json:"user_id"`
+	DeleterID int `json:"deleter_id"`
+	VideoID   int `json:"video_id"`
+}
+
+type WallComment struct {
+	Comment
+	PostID      int `json:"post_id"`
+	PostOwnerID int `json:"post_owner_id"`
+}
+
+type WallCommentDelete struct {
+	OwnerID   int `json:"owner_id"`
+	ID        int `json:"id"`
+	DeleterID int `json:"deleter_id"`
+	PostID    int `json:"post_id"`
+}
+
+type BoardComment struct {
+	Comment
+	TopicID      int `json:"topic_id"`
+	TopicOwnerID int `json:"topic_owner_id"`
+}
+
+type BoardCommentDelete struct {
+	TopicOwnerID int `json:"topic_owner_id"`
+	TopicID      int `json:"topic_id"`
+	ID           int `json:"id"`
+}
+
+type GroupJoin struct {
+	UserID   int    `json:"user_id"`
+	JoinType string `json:"join_type"`
+}
+
+type GroupLeave struct {
+	UserID int `json:"user_id"`
+	Self   int `json:"self"`
+}
+
+type UserBlock struct {
+	AdminID     int    `json:"admin_id"`
+	UserID      int    `json:"user_id"`
+	UnblockDate int64  `json:"unblock_date"`
+	Reason      int    `json:"reason"`
+	Comment     string `json:"comment"`
+}
+
+type UserUnblock struct {
+	AdminID   int `json:"admin_id"`
+	UserID    int `json:"user_id"`
+	ByEndDate int `json:"by_end_date"`
+}
+
+type PollVoteNew struct {
+	OwnerID  int `json:"owner_id"`
+	PollID   int `json:"poll_id"`
+	OptionID int `json:"option_id"`
+	UserID   int `json:"user_id"`
+}
+
+type GroupOfficersEdit struct {
+	AdminID  int `json:"admin_id"`
+	UserID   int `json:"user_id"`
+	LevelOld int `json:"level_old"`
+	LevelNew int `json:"level_new"`
+}
+
+type GroupChangeSettings struct {
+	UserID  int                      `json:"user_id"`
+	Changes map[string]SettingChange `json:"changes"`
+}
+
+// SettingChange holds the old and new value of a community setting,
+// which may be a number or a string depending on the setting.
+type SettingChange struct {
+	OldValue json.RawMessage `json:"old_value"`
+	NewValue json.RawMessage `json:"new_value"`
+}
+
+var eventObjects = map[string]func() interface{}{
+	EventMessageNew:          func() interface{} { return new(NewMessage) },
+	EventMessageReply:        func() interface{} { return new(Message) },
+	EventMessageEdit:         func() interface{} { return new(Message) },
+	EventMessageAllow:        func() interface{} { return new(MessageAllow) },
+	EventMessageDeny:         func() interface{} { return new(MessageDeny) },
+	EventMessageTypingState:  func() interface{} { return new(MessageTypingState) },
+	EventMessageEvent:        func() interface{} { return new(MessageEvent) },
+	EventPhotoNew:            func() interface{} { return new(Photo) },
+	EventPhotoCommentNew:     func() interface{} { return new(PhotoComment) },
+	EventPhotoCommentEdit:    func() interface{} { return new(PhotoComment) },
+	EventPhotoCommentRestore: func() interface{} { return new(PhotoComment) },
+	EventPhotoCommentDelete:  func() interface{} { return new(PhotoCommentDelete) },
+	EventVideoNew:            func() interface{} { return new(Video) },
+	EventVideoCommentNew:     func() interface{} { return new(VideoComment) },
+	EventVideoCommentEdit:    func() interface{} { return new(VideoComment) },
+	EventVideoCommentRestore: func() interface{} { return new(VideoComment) },
+	EventVideoCommentDelete:  func() interface{} { return new(VideoCommentDelete) },
+	EventWallPostNew:         func() interface{} { return new(Post) },
+	EventWallRepost:          func() interface{} { return new(Post) },
+	EventWallReplyNew:        func() interface{} { return new(WallComment) },
+	EventWallReplyEdit:       func() interface{} { return new(WallComment) },
+	EventWallReplyRestore:    func() interface{} { return new(WallComment) },
+	EventWallReplyDelete:     func() interface{} { return new(WallCommentDelete) },
+	EventBoardPostNew:        func() interface{} { return new(BoardComment) },
+	EventBoardPostEdit:       func() interface{} { return new(BoardComment) },
+	EventBoardPostRestore:    func() interface{} { return new(BoardComment) },
+	EventBoardPostDelete:     func() interface{} { return new(BoardCommentDelete) },
+	EventGroupJoin:           func() interface{} { return new(GroupJoin) },
+	EventGroupLeave:          func() interface{} { return new(GroupLeave) },
+	EventUserBlock:           func() interface{} { return new(UserBlock) },
+	EventUserUnblock:         func() interface{} { return new(UserUnblock) },
+	EventPollVoteNew:         func() interface{} { return new(PollVoteNew) },
+	EventGroupOfficersEdit:   func() interface{} { return new(GroupOfficersEdit) },
+	EventGroupChangeSettings: func() interface{} { return new(GroupChangeSettings) },
+}
+
+// Decode returns the event object as a pointer to its type, e.g.
+// *NewMessage for message_new.
+func (u *GroupLPUpdates) Decode() (interface{}, error) {
+	newObject, ok := eventObjects[u.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown event type %q", u.Type)
+	}
+	v := newObject()
+	if err := json.Unmarshal(u.Object, v); err != nil {
+		return nil, fmt.Errorf("%s: %w", u.Type, err)
+	}
+	return v, nil
+}
+
+// onGroupEvent registers f for events of type name. Events that can't
+// be decoded are reported and skipped.
+func onGroupEvent[T any](vk *VkAPI, name string, f func(context.Context, *T)) Unsubscribe {
+	return vk.groupLPCallback(name, func(ctx context.Context, event *GroupLPUpdates) {
+		v := new(T)
+		if err := json.Unmarshal(event.Object, v); err != nil {
+			vk.reportEventError(ctx, fmt.Errorf("%s: %w", name, err))
+			return
+		}
+		f(ctx, v)
+	})
+}
+
+// OnMessageNew registers f for incoming messages.
+func (vk *VkAPI) OnMessageNew(f func(ctx context.Context, m *NewMessage)) Unsubscribe {
+	return onGroupEvent(vk, EventMessageNew, f)
+}
+
+// OnMessageReply registers f for messages sent by the community.
+func (vk *VkAPI) OnMessageReply(f func(ctx context.Context, m *Message)) Unsubscribe {
+	return onGroupEvent(vk, EventMessageReply, f)
+}
+
+// OnMessageEdit registers f for edited messages.
+func (vk *VkAPI) OnMessageEdit(f func(ctx context.Context, m *Message)) Unsubscribe {
+	return onGroupEvent(vk, EventMessageEdit, f)
+}
+
+// OnMessageAllow registers f for users allowing messages from the community.
+func (vk *VkAPI) OnMessageAllow(f func(ctx context.Context, e *MessageAllow)) Unsubscribe {
+	return onGroupEvent(vk, EventMessageAllow, f)
+}
+
+// OnMessageDeny registers f for users denying messages from the community.
+func (vk *VkAPI) OnMessageDeny(f func(ctx context.Context, e *MessageDeny)) Unsubscribe {
+	return onGroupEvent(vk, EventMessageDeny, f)
+}
+
+// OnMessageTypingState registers f for users typing a message.
+func (vk *VkAPI) OnMessageTypingState(f func(ctx context.Context, e *MessageTypingState)) Unsubscribe {
+	return onGroupEvent(vk, EventMessageTypingState, f)
+}
+
+// OnMessageEvent registers f for callback button presses.
+func (vk *VkAPI) OnMessageEvent(f func(ctx context.Context, e *MessageEvent)) Unsubscribe {
+	return onGroupEvent(vk, EventMessageEvent, f)
+}
+
+// OnPhotoNew registers f for new photos.
+func (vk *VkAPI) OnPhotoNew(f func(ctx context.Context, p *Photo)) Unsubscribe {
+	return onGroupEvent(vk, EventPhotoNew, f)
+}
+
+// OnPhotoCommentNew registers f for new photo comments.
+func (vk *VkAPI) OnPhotoCommentNew(f func(ctx context.Context, c *PhotoComment)) Unsubscribe {
+	return onGroupEvent(vk, EventPhotoCommentNew, f)
+}
+
+// OnPhotoCommentEdit registers f for edited photo comments.
+func (vk *VkAPI) OnPhotoCommentEdit(f func(ctx context.Context, c *PhotoComment)) Unsubscribe {
+	return onGroupEvent(vk, EventPhotoCommentEdit, f)
+}
+
+// OnPhotoCommentRestore registers f for restored photo comments.
+func (vk *VkAPI) OnPhotoCommentRestore(f func(ctx context.Context, c *PhotoComment)) Unsubscribe {
+	return onGroupEvent(vk, EventPhotoCommentRestore, f)
+}
+
+// OnPhotoCommentDelete registers f for deleted photo comments.
+func (vk *VkAPI) OnPhotoCommentDelete(f func(ctx context.Context, c *PhotoCommentDelete)) Unsubscribe {
+	return onGroupEvent(vk, EventPhotoCommentDelete, f)
+}
+
+// OnVideoNew registers f for new videos.
+func (vk *VkAPI) OnVideoNew(f func(ctx context.Context, v *Video)) Unsubscribe {
+	return onGroupEvent(vk, EventVideoNew, f)
+}
+
+// OnVideoCommentNew registers f for new video comments.
+func (vk *VkAPI) OnVideoCommentNew(f func(ctx context.Context, c *VideoComment)) Unsubscribe {
+	return onGroupEvent(vk, EventVideoCommentNew, f)
+}
+
+// OnVideoCommentEdit registers f for edited video comments.
+func (vk *VkAPI) OnVideoCommentEdit(f func(ctx context.Context, c *VideoComment)) Unsubscribe {
+	return onGroupEvent(vk, EventVideoCommentEdit, f)
+}
+
+// OnVideoCommentRestore registers f for restored video comments.
+func (vk *VkAPI) OnVideoCommentRestore(f func(ctx context.Context, c *VideoComment)) Unsubscribe {
+	return onGroupEvent(vk, EventVideoCommentRestore, f)
+}
+
+// OnVideoCommentDelete registers f for deleted video comments.
+func (vk *VkAPI) OnVideoCommentDelete(f func(ctx context.Context, c *VideoCommentDelete)) Unsubscribe {
+	return onGroupEvent(vk, EventVideoCommentDelete, f)
+}
+
+// OnWallPostNew registers f for new wall posts.
+func (vk *VkAPI) OnWallPostNew(f func(ctx context.Context, p *Post)) Unsubscribe {
+	return onGroupEvent(vk, EventWallPostNew, f)
+}
+
+// OnWallRepost registers f for reposts of community posts.
+func (vk *VkAPI) OnWallRepost(f func(ctx context.Context, p *Post)) Unsubscribe {
+	return onGroupEvent(vk, EventWallRepost, f)
+}
+
+// OnWallReplyNew registers f for new wall comments.
+func (vk *VkAPI) OnWallReplyNew(f func(ctx context.Context, c *WallComment)) Unsubscribe {
+	return onGroupEvent(vk, EventWallReplyNew, f)
+}
+
+// OnWallReplyEdit registers f for edited wall comments.
+func (vk *VkAPI) OnWallReplyEdit(f func(ctx context.Context, c *WallComment)) Unsubscribe {
+	return onGroupEvent(vk, EventWallReplyEdit, f)
+}
+
+// OnWallReplyRestore registers f for restored wall comments.
+func (vk *VkAPI) OnWallReplyRestore(f func(ctx context.Context, c *WallComment)) Unsubscribe {
+	return onGroupEvent(vk, EventWallReplyRestore, f)
+}
+
+// OnWallReplyDelete registers f for deleted wall comments.
+func (vk *VkAPI) OnWallReplyDelete(f func(ctx context.Context, c *WallCommentDelete)) Unsubscribe {
+	return onGroupEvent(vk, EventWallReplyDelete, f)
+}
+
+// OnBoardPostNew registers f for new board comments.
+func (vk *VkAPI) OnBoardPostNew(f func(ctx context.Context, c *BoardComment)) Unsubscribe {
+	return onGroupEvent(vk, EventBoardPostNew, f)
+}
+
+// OnBoardPostEdit registers f for edited board comments.
+func (vk *VkAPI) OnBoardPostEdit(f func(ctx context.Context, c *BoardComment)) Unsubscribe {
+	return onGroupEvent(vk, EventBoardPostEdit, f)
+}
+
+// OnBoardPostRestore registers f for restored board comments.
+func (vk *VkAPI) OnBoardPostRestore(f func(ctx context.Context, c *BoardComment)) Unsubscribe {
+	return onGroupEvent(vk, EventBoardPostRestore, f)
+}
+
+// OnBoardPostDelete registers f for deleted board comments.
+func (vk *VkAPI) OnBoardPostDelete(f func(ctx context.Context, c *BoardCommentDelete)) Unsubscribe {
+	return onGroupEvent(vk, EventBoardPostDelete, f)
+}
+
+// OnGroupJoin registers f for users joining the community.
+func (vk *VkAPI) OnGroupJoin(f func(ctx context.Context, e *GroupJoin)) Unsubscribe {
+	return onGroupEvent(vk, EventGroupJoin, f)
+}
+
+// OnGroupLeave registers f for users leaving the community.
+func (vk *VkAPI) OnGroupLeave(f func(ctx context.Context, e *GroupLeave)) Unsubscribe {
+	return onGroupEvent(vk, EventGroupLeave, f)
+}
+
+// OnUserBlock registers f for users added to the blacklist.
+func (vk *VkAPI) OnUserBlock(f func(ctx context.Context, e *UserBlock)) Unsubscribe {
+	return onGroupEvent(vk, EventUserBlock, f)
+}
+
+// OnUserUnblock registers f for users removed from the blacklist.
+func (vk *VkAPI) OnUserUnblock(f func(ctx context.Context, e *UserUnblock)) Unsubscribe {
+	return onGroupEvent(vk, EventUserUnblock, f)
+}
+
+// OnPollVoteNew registers f for new poll votes.
+func (vk *VkAPI) OnPollVoteNew(f func(ctx context.Context, e *PollVoteNew)) Unsubscribe {
+	return onGroupEvent(vk, EventPollVoteNew, f)
+}
+
+// OnGroupOfficersEdit registers f for changes of community managers.
+func (vk *VkAPI) OnGroupOfficersEdit(f func(ctx context.Context, e *GroupOfficersEdit)) Unsubscribe {
+	return onGroupEvent(vk, EventGroupOfficersEdit, f)
+}
+
+// OnGroupChangeSettings registers f for changes of community settings.
+func (vk *VkAPI) OnGroupChangeSettings(f func(ctx context.Context, e *GroupChangeSettings)) Unsubscribe {
+	return onGroupEvent(vk, EventGroupChangeSettings, f)
+}
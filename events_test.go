@@ -0,0 +1,96 @@
+package vkapi
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		update  GroupLPUpdates
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:   "message_new",
+			update: GroupLPUpdates{Type: EventMessageNew, Object: json.RawMessage(`{"message":{"id":1,"peer_id":2,"text":"hi"}}`)},
+			want:   &NewMessage{Message: Message{ID: 1, PeerID: 2, Text: "hi"}},
+		},
+		{
+			name:   "message_deny",
+			update: GroupLPUpdates{Type: EventMessageDeny, Object: json.RawMessage(`{"user_id":5}`)},
+			want:   &MessageDeny{UserID: 5},
+		},
+		{
+			name:   "group_leave",
+			update: GroupLPUpdates{Type: EventGroupLeave, Object: json.RawMessage(`{"user_id":5,"self":1}`)},
+			want:   &GroupLeave{UserID: 5, Self: 1},
+		},
+		{
+			name:    "unknown type",
+			update:  GroupLPUpdates{Type: "app_payload", Object: json.RawMessage(`{}`)},
+			wantErr: true,
+		},
+		{
+			name:    "malformed object",
+			update:  GroupLPUpdates{Type: EventMessageDeny, Object: json.RawMessage(`{"user_id":"x"}`)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.update.Decode()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventObjects(t *testing.T) {
+	for name, newObject := range eventObjects {
+		if reflect.TypeOf(newObject()).Kind() != reflect.Ptr {
+			t.Errorf("%s: object is not a pointer", name)
+		}
+	}
+}
+
+func TestOnGroupEvent(t *testing.T) {
+	tests := []struct {
+		name       string
+		object     string
+		wantCalled bool
+		wantErr    bool
+	}{
+		{"decoded", `{"message":{"id":1,"text":"hi"}}`, true, false},
+		{"malformed", `{"message":[]}`, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vk := NewVkAPI("token")
+			var reported error
+			vk.EventErrorHandler = func(_ context.Context, err error) { reported = err }
+
+			var got *NewMessage
+			vk.OnMessageNew(func(_ context.Context, m *NewMessage) { got = m })
+			vk.OnMessageReply(func(context.Context, *Message) { t.Error("message_reply handler called") })
+			vk.handleGroupLPCallback(context.Background(), EventMessageNew, &GroupLPUpdates{
+				Type:   EventMessageNew,
+				Object: json.RawMessage(tt.object),
+			})
+
+			if (got != nil) != tt.wantCalled || got != nil && got.Message.Text != "hi" {
+				t.Errorf("handler got %+v", got)
+			}
+			if (reported != nil) != tt.wantErr {
+				t.Errorf("reported %v, wantErr %v", reported, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/seilem/vk-golang-sdk
 
-go 1.14
+go 1.21
@@ -2,133 +2,82 @@ package vkapi
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"log"
-	"net/http"
 	"net/url"
-	"strconv"
 )
 
 type GroupGetLPServerReq struct {
-	GroupID int64
+	GroupID int64 `vk:"group_id,omitempty"`
 }
 
 func (GroupGetLPServerReq) Name() string {
 	return "groups.getLongPollServer"
 }
 
+// EncodeValues returns the params of g.
+func (g *GroupGetLPServerReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(g)
+}
+
+// Values returns the params of g.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
 func (g *GroupGetLPServerReq) Values() url.Values {
-	v := url.Values{}
+	values, _ := EncodeValues(g)
+	return values
+}
 
-	if g.GroupID > 0 {
-		v.Set("group_id", strconv.FormatInt(g.GroupID, 10))
-	}
-	return v
+func (g GroupGetLPServerReq) Validate() error {
+	return validate(g.Name(),
+		required("group_id", g.GroupID > 0),
+	)
 }
 
 // GroupGetLPServer returns data for Bots Long Poll API connection.
 //
 // See https://vk.com/dev/groups.getLongPollServer
 func (vk *VkAPI) GroupGetLPServer(v *GroupGetLPServerReq) (*LPServer, error) {
-	resp, err := vk.MakeRequest(v.Name(), v.Values())
-	if err != nil {
-		return nil, err
-	}
+	return vk.GroupGetLPServerContext(context.Background(), v)
+}
 
-	var apiResp LPServer
-	if err := json.Unmarshal(resp.Response, &apiResp); err != nil {
-		return nil, err
-	}
-	return &apiResp, nil
+// GroupGetLPServerContext is like GroupGetLPServer but uses ctx for the request.
+func (vk *VkAPI) GroupGetLPServerContext(ctx context.Context, v *GroupGetLPServerReq) (*LPServer, error) {
+	return Call[*LPServer](ctx, vk, v)
 }
 
+// GroupLPServ polls community events and passes them to the callbacks
+// registered with GroupLPCallback. Use GroupLongPoll for more control.
 func (vk *VkAPI) GroupLPServ(groupID int64) error {
-	return vk.groupLongPoll(context.Background(), groupID)
+	return vk.GroupLPServContext(context.Background(), groupID)
 }
 
-func (vk *VkAPI) GroupLPCallback(name string, f func(event *GroupLPUpdates)) {
-	if _, exists := vk.groupLPSubs.events[name]; !exists {
-		vk.groupLPSubs.events[name] = f
-	}
+// GroupLPServContext is like GroupLPServ but stops polling when ctx is done.
+func (vk *VkAPI) GroupLPServContext(ctx context.Context, groupID int64) error {
+	return NewGroupLongPoll(vk, groupID).Run(ctx)
 }
 
-func (vk *VkAPI) groupLongPoll(ctx context.Context, groupID int64) error {
-	server, err := vk.GroupGetLPServer(&GroupGetLPServerReq{
-		GroupID: groupID,
+// GroupLPCallback registers f for community events of type name.
+// Several callbacks of one type run in order of registration.
+// See OnMessageNew and the other On methods for decoded events.
+func (vk *VkAPI) GroupLPCallback(name string, f func(event *GroupLPUpdates)) Unsubscribe {
+	return vk.groupLPCallback(name, func(_ context.Context, event *GroupLPUpdates) {
+		f(event)
 	})
-	if err != nil {
-		log.Print("Get group lp server is failed: %w", err)
-		return err
-	}
-
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		default:
-		}
-
-		serverURL := fmt.Sprintf(
-			"%s?act=a_check&key=%s&ts=%s&wait=25",
-			server.Server, server.Key, server.TS)
-		req, err := http.NewRequest(http.MethodGet, serverURL, nil)
-		if err != nil {
-			log.Print("Group lp server connection failed: %w", err)
-			continue
-		}
-
-		resp, err := vk.Client.Do(req)
-		if err != nil {
-			log.Print("Group lp request connection failed: %w", err)
-			continue
-		}
-
-		body, err := ioutil.ReadAll(resp.Body)
-
-		var e GroupLPEvent
-		if err := json.Unmarshal(body, &e); err != nil {
-			log.Print("Unmarshalling error: %w", err)
-		}
-
-		switch true {
-		case e.Failed == 0:
-			for _, update := range e.Updates {
-				vk.handleGroupLPCallback(update.Type, &update)
-			}
-			server.TS = e.TS
-		case e.Failed == 1:
-			server.TS = e.TS
-		case e.Failed == 2 || e.Failed == 3:
-			newServer, err := vk.GroupGetLPServer(&GroupGetLPServerReq{
-				GroupID: groupID,
-			})
-			if err != nil {
-				log.Print("Get group lp server is failed: %w", err)
-				continue
-			}
-
-			if e.Failed == 2 {
-				server.Key = newServer.Key
-			}
-
-			if e.Failed == 3 {
-				server.Key = newServer.Key
-				server.TS = newServer.TS
-			}
-		}
+}
 
-		if resp != nil {
-			resp.Body.Close()
-		}
-	}
+// OnAnyGroupEvent registers f for community events of every type.
+// It runs after the callbacks of the event type.
+func (vk *VkAPI) OnAnyGroupEvent(f func(ctx context.Context, event *GroupLPUpdates)) Unsubscribe {
+	return vk.groupEvents.addAny(func(ctx context.Context, _ string, event *GroupLPUpdates) {
+		f(ctx, event)
+	})
 }
 
-func (vk *VkAPI) handleGroupLPCallback(name string, event *GroupLPUpdates) {
-	_, exists := vk.groupLPSubs.events[name]
+func (vk *VkAPI) groupLPCallback(name string, f func(ctx context.Context, event *GroupLPUpdates)) Unsubscribe {
+	return vk.groupEvents.add(name, func(ctx context.Context, _ string, event *GroupLPUpdates) {
+		f(ctx, event)
+	})
+}
 
-	if exists {
-		vk.groupLPSubs.events[name](event)
-	}
+func (vk *VkAPI) handleGroupLPCallback(ctx context.Context, name string, event *GroupLPUpdates) {
+	vk.groupEvents.dispatch(ctx, name, event, vk.reportEventError)
 }
@@ -0,0 +1,46 @@
+package vkapi
+
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+// Invocation is a single attempt to call an API method.
+type Invocation struct {
+	Method string
+	// Params are the request params without the access token.
+	Params url.Values
+	// Body is the raw response body. It is set once the response is read.
+	Body []byte
+	// Latency is the duration of the HTTP round trip. It is set once
+	// the request is done.
+	Latency time.Duration
+
+	token string
+}
+
+// Invoker sends an invocation and returns the decoded response.
+type Invoker func(ctx context.Context, inv *Invocation) (*APIResponse, error)
+
+// Interceptor wraps an Invoker to observe or alter requests.
+type Interceptor func(next Invoker) Invoker
+
+// Use appends interceptors to the chain wrapping every request attempt.
+// The first interceptor added is the outermost one.
+func (vk *VkAPI) Use(interceptors ...Interceptor) {
+	vk.mu.Lock()
+	defer vk.mu.Unlock()
+	vk.interceptors = append(vk.interceptors, interceptors...)
+}
+
+func (vk *VkAPI) invoker() Invoker {
+	vk.mu.RLock()
+	defer vk.mu.RUnlock()
+
+	next := Invoker(vk.transport)
+	for i := len(vk.interceptors) - 1; i >= 0; i-- {
+		next = vk.interceptors[i](next)
+	}
+	return next
+}
@@ -0,0 +1,105 @@
+package vkapi_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+	"github.com/seilem/vk-golang-sdk/vktest"
+)
+
+func TestUse(t *testing.T) {
+	srv := vktest.NewServer()
+	defer srv.Close()
+	srv.Handle("users.get", []vkapi.User{{ID: 1}})
+
+	var order []string
+	trace := func(name string) vkapi.Interceptor {
+		return func(next vkapi.Invoker) vkapi.Invoker {
+			return func(ctx context.Context, inv *vkapi.Invocation) (*vkapi.APIResponse, error) {
+				order = append(order, name+" before")
+				resp, err := next(ctx, inv)
+				order = append(order, name+" after")
+				if len(inv.Body) == 0 {
+					t.Errorf("%s: Body is empty after the request", name)
+				}
+				return resp, err
+			}
+		}
+	}
+
+	vk := srv.Client("token")
+	vk.Use(trace("outer"), trace("inner"))
+	if _, err := vk.UsersGetContext(context.Background(), &vkapi.UsersGetReq{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"outer before", "inner before", "inner after", "outer after"}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestUseAltersRequest(t *testing.T) {
+	errBlocked := errors.New("blocked")
+
+	tests := []struct {
+		name        string
+		interceptor vkapi.Interceptor
+		wantErr     error
+		wantLang    string
+		wantSent    int
+	}{
+		{
+			name: "adds param",
+			interceptor: func(next vkapi.Invoker) vkapi.Invoker {
+				return func(ctx context.Context, inv *vkapi.Invocation) (*vkapi.APIResponse, error) {
+					inv.Params.Set("lang", "en")
+					return next(ctx, inv)
+				}
+			},
+			wantLang: "en",
+			wantSent: 1,
+		},
+		{
+			name: "short circuits",
+			interceptor: func(next vkapi.Invoker) vkapi.Invoker {
+				return func(ctx context.Context, inv *vkapi.Invocation) (*vkapi.APIResponse, error) {
+					return nil, errBlocked
+				}
+			},
+			wantErr: errBlocked,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := vktest.NewServer()
+			defer srv.Close()
+			srv.HandleFunc("users.get", func(url.Values) (interface{}, error) {
+				return []vkapi.User{}, nil
+			})
+
+			vk := srv.Client("token")
+			vk.Use(tt.interceptor)
+			_, err := vk.UsersGetContext(context.Background(), &vkapi.UsersGetReq{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			reqs := srv.Requests("users.get")
+			if len(reqs) != tt.wantSent {
+				t.Fatalf("sent %d requests, want %d", len(reqs), tt.wantSent)
+			}
+			if tt.wantSent > 0 && reqs[0].Get("lang") != tt.wantLang {
+				t.Errorf("lang = %q, want %q", reqs[0].Get("lang"), tt.wantLang)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package vkapi
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MaxUsersPerRequest is the maximum number of ids accepted by users.get.
+const MaxUsersPerRequest = 1000
+
+// ErrUserNotFound is returned by UserLoader for ids missing in the
+// users.get response.
+var ErrUserNotFound = errors.New("user not found")
+
+// UserDeactivatedError is returned by UserLoader together with the user
+// for deleted or banned accounts.
+type UserDeactivatedError struct {
+	ID     int
+	Reason string
+}
+
+func (e *UserDeactivatedError) Error() string {
+	return "user " + strconv.Itoa(e.ID) + " is " + e.Reason
+}
+
+// UserLoader collects ids requested within a short window and loads
+// them with as few users.get calls as possible. Concurrent loads of
+// the same id share one result.
+type UserLoader struct {
+	// Fields and NameCase are passed to users.get.
+	Fields   []string
+	NameCase string
+
+	vk     *VkAPI
+	window time.Duration
+
+	mu      sync.Mutex
+	pending map[int][]userWaiter
+	timer   *time.Timer
+}
+
+type userWaiter struct {
+	ctx  context.Context
+	done chan userResult
+}
+
+type userResult struct {
+	user *User
+	err  error
+}
+
+// NewUserLoader returns a loader that waits window for more ids
+// before calling users.get.
+func NewUserLoader(vk *VkAPI, window time.Duration) *UserLoader {
+	return &UserLoader{
+		vk:      vk,
+		window:  window,
+		pending: make(map[int][]userWaiter),
+	}
+}
+
+// Load returns the user with the given id. Deactivated users are
+// returned along with a *UserDeactivatedError.
+func (l *UserLoader) Load(ctx context.Context, id int) (*User, error) {
+	w := userWaiter{ctx: ctx, done: make(chan userResult, 1)}
+
+	l.mu.Lock()
+	l.pending[id] = append(l.pending[id], w)
+	switch {
+	case len(l.pending) >= MaxUsersPerRequest:
+		batch := l.take()
+		go l.load(batch)
+	case len(l.pending) == 1 && len(l.pending[id]) == 1:
+		l.timer = time.AfterFunc(l.window, l.flush)
+	}
+	l.mu.Unlock()
+
+	select {
+	case r := <-w.done:
+		return r.user, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// LoadMany loads several users at once. Results and errors are in
+// the order of ids.
+func (l *UserLoader) LoadMany(ctx context.Context, ids []int) ([]*User, []error) {
+	users := make([]*User, len(ids))
+	errs := make([]error, len(ids))
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i, id int) {
+			defer wg.Done()
+			users[i], errs[i] = l.Load(ctx, id)
+		}(i, id)
+	}
+	wg.Wait()
+	return users, errs
+}
+
+// take must be called with l.mu held.
+func (l *UserLoader) take() map[int][]userWaiter {
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	batch := l.pending
+	l.pending = make(map[int][]userWaiter)
+	return batch
+}
+
+func (l *UserLoader) flush() {
+	l.mu.Lock()
+	batch := l.take()
+	l.mu.Unlock()
+
+	if len(batch) > 0 {
+		l.load(batch)
+	}
+}
+
+func (l *UserLoader) load(batch map[int][]userWaiter) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for _, waiters := range batch {
+			for _, w := range waiters {
+				select {
+				case <-w.ctx.Done():
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+		cancel()
+	}()
+
+	ids := make([]string, 0, len(batch))
+	for id := range batch {
+		ids = append(ids, strconv.Itoa(id))
+	}
+
+	results := make(map[int]userResult, len(batch))
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > MaxUsersPerRequest {
+			n = MaxUsersPerRequest
+		}
+		chunk := ids[:n]
+		ids = ids[n:]
+
+		users, err := l.vk.UsersGetContext(ctx, &UsersGetReq{
+			UserIDs:  chunk,
+			Fields:   l.Fields,
+			NameCase: l.NameCase,
+		})
+		if err != nil {
+			for _, id := range chunk {
+				n, _ := strconv.Atoi(id)
+				results[n] = userResult{err: err}
+			}
+			continue
+		}
+
+		for i := range users {
+			u := &users[i]
+			r := userResult{user: u}
+			if u.Deactivated != "" {
+				r.err = &UserDeactivatedError{ID: u.ID, Reason: u.Deactivated}
+			}
+			results[u.ID] = r
+		}
+	}
+
+	for id, waiters := range batch {
+		r, ok := results[id]
+		if !ok {
+			r = userResult{err: ErrUserNotFound}
+		}
+		for _, w := range waiters {
+			w.done <- r
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package vkapi_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+	"github.com/seilem/vk-golang-sdk/vktest"
+)
+
+// handleUsers answers users.get with every requested user except 404,
+// marking 13 as deleted.
+func handleUsers(srv *vktest.Server) {
+	srv.HandleFunc("users.get", func(params url.Values) (interface{}, error) {
+		var users []vkapi.User
+		for _, s := range strings.Split(params.Get("user_ids"), ",") {
+			id, _ := strconv.Atoi(s)
+			switch id {
+			case 404:
+				continue
+			case 13:
+				users = append(users, vkapi.User{ID: id, Deactivated: "deleted"})
+			default:
+				users = append(users, vkapi.User{ID: id, FirstName: "user" + s})
+			}
+		}
+		return users, nil
+	})
+}
+
+func TestUserLoader(t *testing.T) {
+	tests := []struct {
+		name      string
+		ids       []int
+		wantCalls int
+		wantErrs  map[int]error
+	}{
+		{"one", []int{1}, 1, nil},
+		{"merged", []int{1, 2, 3}, 1, nil},
+		{"duplicates", []int{1, 1, 2, 1}, 1, nil},
+		{"not found", []int{1, 404}, 1, map[int]error{404: vkapi.ErrUserNotFound}},
+		{"deactivated", []int{13}, 1, map[int]error{13: &vkapi.UserDeactivatedError{}}},
+		// The first chunk is sent once full, the rest may be split
+		// further if ids arrive slower than the window.
+		{"chunked", seq(vkapi.MaxUsersPerRequest + 500), 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := vktest.NewServer()
+			defer srv.Close()
+			handleUsers(srv)
+
+			l := vkapi.NewUserLoader(srv.Client("token"), 50*time.Millisecond)
+			users, errs := l.LoadMany(context.Background(), tt.ids)
+			for i, id := range tt.ids {
+				wantErr := tt.wantErrs[id]
+				var deactivated *vkapi.UserDeactivatedError
+				switch {
+				case errors.As(wantErr, &deactivated):
+					if !errors.As(errs[i], &deactivated) || deactivated.ID != id || users[i] == nil {
+						t.Errorf("user %d: %+v, %v, want the user with a UserDeactivatedError", id, users[i], errs[i])
+					}
+				case wantErr != nil:
+					if !errors.Is(errs[i], wantErr) || users[i] != nil {
+						t.Errorf("user %d: %+v, %v, want %v", id, users[i], errs[i], wantErr)
+					}
+				default:
+					if errs[i] != nil || users[i] == nil || users[i].ID != id {
+						t.Errorf("user %d: %+v, %v", id, users[i], errs[i])
+					}
+				}
+			}
+			reqs := srv.Requests("users.get")
+			if tt.wantCalls > 0 && len(reqs) != tt.wantCalls {
+				t.Errorf("users.get called %d times, want %d", len(reqs), tt.wantCalls)
+			}
+			for _, params := range reqs {
+				if n := len(strings.Split(params.Get("user_ids"), ",")); n > vkapi.MaxUsersPerRequest {
+					t.Errorf("users.get called with %d ids", n)
+				}
+			}
+		})
+	}
+}
+
+func TestUserLoaderError(t *testing.T) {
+	srv := vktest.NewServer()
+	defer srv.Close()
+	srv.HandleError("users.get", vkapi.ErrAccessDenied)
+
+	l := vkapi.NewUserLoader(srv.Client("token"), time.Millisecond)
+	_, errs := l.LoadMany(context.Background(), []int{1, 2})
+	for i, err := range errs {
+		if !errors.Is(err, vkapi.ErrAccessDenied) {
+			t.Errorf("errs[%d] = %v, want %v", i, err, vkapi.ErrAccessDenied)
+		}
+	}
+}
+
+func TestUserLoaderContextDone(t *testing.T) {
+	srv := vktest.NewServer()
+	defer srv.Close()
+	handleUsers(srv)
+
+	l := vkapi.NewUserLoader(srv.Client("token"), time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Load(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func seq(n int) []int {
+	ids := make([]int, n)
+	for i := range ids {
+		ids[i] = i + 1000
+	}
+	return ids
+}
@@ -0,0 +1,34 @@
+package vkapi
+
+import "log/slog"
+
+// Logger receives diagnostic messages of the SDK. Args are key-value
+// pairs, as in log/slog. A *slog.Logger satisfies Logger.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// NewSlogLogger returns a Logger writing to l, or to slog.Default() if l is nil.
+func NewSlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		return slog.Default()
+	}
+	return l
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
+func (vk *VkAPI) logger() Logger {
+	if vk.Logger == nil {
+		return nopLogger{}
+	}
+	return vk.Logger
+}
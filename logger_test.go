@@ -0,0 +1,54 @@
+package vkapi_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+	"github.com/seilem/vk-golang-sdk/vktest"
+)
+
+func TestLogger(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		level slog.Level
+		want  []string
+	}{
+		{"failed request", vkapi.ErrAccessDenied, slog.LevelDebug, []string{"api request failed", "method=users.get", "error="}},
+		{"below level", vkapi.ErrAccessDenied, slog.LevelInfo, nil},
+		{"successful request", nil, slog.LevelDebug, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := vktest.NewServer()
+			defer srv.Close()
+			if tt.err != nil {
+				srv.HandleError("users.get", tt.err)
+			} else {
+				srv.Handle("users.get", []vkapi.User{})
+			}
+
+			var buf bytes.Buffer
+			vk := srv.Client("token")
+			vk.Logger = vkapi.NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: tt.level})))
+			vk.UsersGetContext(context.Background(), &vkapi.UsersGetReq{})
+
+			out := buf.String()
+			if tt.want == nil && out != "" {
+				t.Errorf("logged %q, want nothing", out)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(out, w) {
+					t.Errorf("log %q does not contain %q", out, w)
+				}
+			}
+			if strings.Contains(out, "token") {
+				t.Errorf("log %q contains the access token", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package vkapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Long poll wait limits in seconds.
+//
+// See https://vk.com/dev/bots_longpoll
+const (
+	DefaultLPWait = 25
+	MaxLPWait     = 90
+)
+
+// ErrLongPollRunning is returned by Run if the long poll is already running.
+var ErrLongPollRunning = errors.New("long poll is already running")
+
+// GroupLongPoll receives community events from the Bots Long Poll API
+// and passes them to the callbacks registered with GroupLPCallback.
+//
+// Run may be called again after it returns; polling resumes from the
+// last received ts. Delivery is at least once: updates of a batch
+// interrupted by shutdown are delivered again by the next Run, so
+// callbacks should tolerate duplicates, e.g. by their event_id.
+type GroupLongPoll struct {
+	GroupID int64
+	// Wait is how long the server holds a request without events,
+	// in seconds. It is DefaultLPWait if zero and at most MaxLPWait.
+	Wait int
+	// BaseDelay is the pause after a failed request. It is doubled
+	// after every consecutive failure up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// OnConnected is called when Run receives its first server.
+	OnConnected func(server LPServer)
+	// OnKeyRefreshed is called when an expired key is replaced.
+	OnKeyRefreshed func(server LPServer)
+	// OnTSReset is called when the history is lost and polling
+	// continues from ts. Events before it are not delivered.
+	OnTSReset func(ts string)
+	// OnStopped is called with the error Run is about to return.
+	OnStopped func(err error)
+
+	vk      *VkAPI
+	running atomic.Bool
+	wg      sync.WaitGroup
+	ts      string
+}
+
+// NewGroupLongPoll returns a long poll of the community groupID.
+func NewGroupLongPoll(vk *VkAPI, groupID int64) *GroupLongPoll {
+	return &GroupLongPoll{
+		GroupID:   groupID,
+		Wait:      DefaultLPWait,
+		BaseDelay: time.Second,
+		MaxDelay:  time.Minute,
+		vk:        vk,
+	}
+}
+
+// Run polls for events until ctx is done or the long poll server
+// can't be obtained. Failed requests are repeated with a backoff.
+// Run returns nil when ctx is done, after running callbacks finish.
+func (lp *GroupLongPoll) Run(ctx context.Context) (err error) {
+	if !lp.running.CompareAndSwap(false, true) {
+		return ErrLongPollRunning
+	}
+	defer func() {
+		lp.wg.Wait()
+		lp.running.Store(false)
+		if lp.OnStopped != nil {
+			lp.OnStopped(err)
+		}
+	}()
+
+	log := lp.vk.logger()
+	server, err := lp.server(ctx)
+	if err != nil {
+		return lp.stopped(ctx, err)
+	}
+	if lp.ts != "" {
+		server.TS = lp.ts
+	}
+	if lp.OnConnected != nil {
+		lp.OnConnected(*server)
+	}
+
+	failures := 0
+	for {
+		e, err := lp.check(ctx, server)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			failures++
+			log.Error("group long poll check failed", "group_id", lp.GroupID, "ts", server.TS, "error", err)
+			if sleep(ctx, backoff(lp.BaseDelay, lp.MaxDelay, failures)) != nil {
+				return nil
+			}
+			continue
+		}
+		failures = 0
+
+		switch e.Failed {
+		case 0:
+			for i := range e.Updates {
+				if err := lp.dispatch(ctx, &e.Updates[i]); err != nil {
+					return lp.stopped(ctx, err)
+				}
+			}
+			server.TS = e.TS
+		case 1:
+			log.Debug("group long poll history outdated", "group_id", lp.GroupID, "ts", server.TS, "failed", e.Failed)
+			server.TS = e.TS
+			if lp.OnTSReset != nil {
+				lp.OnTSReset(server.TS)
+			}
+		case 2, 3:
+			log.Info("group long poll key expired", "group_id", lp.GroupID, "ts", server.TS, "failed", e.Failed)
+			newServer, err := lp.server(ctx)
+			if err != nil {
+				return lp.stopped(ctx, err)
+			}
+
+			server.Key = newServer.Key
+			if lp.OnKeyRefreshed != nil {
+				lp.OnKeyRefreshed(*server)
+			}
+			if e.Failed == 3 {
+				server.TS = newServer.TS
+				if lp.OnTSReset != nil {
+					lp.OnTSReset(server.TS)
+				}
+			}
+		default:
+			return fmt.Errorf("group long poll: unexpected failed %d", e.Failed)
+		}
+		lp.ts = server.TS
+	}
+}
+
+// stopped returns nil if err was caused by ctx being done.
+func (lp *GroupLongPoll) stopped(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func (lp *GroupLongPoll) wait() int {
+	switch {
+	case lp.Wait <= 0:
+		return DefaultLPWait
+	case lp.Wait > MaxLPWait:
+		return MaxLPWait
+	}
+	return lp.Wait
+}
+
+// server gets a long poll server, repeating the request after
+// transport errors. API errors are returned.
+func (lp *GroupLongPoll) server(ctx context.Context) (*LPServer, error) {
+	for attempt := 1; ; attempt++ {
+		server, err := lp.vk.GroupGetLPServerContext(ctx, &GroupGetLPServerReq{
+			GroupID: lp.GroupID,
+		})
+		if err == nil {
+			return server, nil
+		}
+
+		lp.vk.logger().Error("get group long poll server failed", "group_id", lp.GroupID, "error", err)
+		if errors.As(err, new(APIError)) || errors.As(err, new(*ParamError)) {
+			return nil, err
+		}
+		if err := sleep(ctx, backoff(lp.BaseDelay, lp.MaxDelay, attempt)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (lp *GroupLongPoll) check(ctx context.Context, server *LPServer) (*GroupLPEvent, error) {
+	serverURL := lp.vk.lpURL(server.Server, url.Values{
+		"act":  {"a_check"},
+		"key":  {server.Key},
+		"ts":   {server.TS},
+		"wait": {strconv.Itoa(lp.wait())},
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, serverURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := lp.vk.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var e GroupLPEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// dispatch passes update to the callbacks. Their ctx is not cancelled
+// with the one of Run, so requests they make can complete on shutdown.
+// If ctx is done while the Dispatcher is full, Run returns without
+// moving the ts and the next Run receives the whole batch again,
+// including updates already dispatched.
+func (lp *GroupLongPoll) dispatch(ctx context.Context, update *GroupLPUpdates) error {
+	hctx := context.WithoutCancel(ctx)
+	lp.wg.Add(1)
+	err := lp.vk.dispatchEvent(ctx, update.orderKey(), func() {
+		defer lp.wg.Done()
+		lp.vk.handleGroupLPCallback(hctx, update.Type, update)
+	})
+	if err != nil {
+		lp.wg.Done()
+	}
+	return err
+}
@@ -0,0 +1,219 @@
+package vkapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+	"github.com/seilem/vk-golang-sdk/vktest"
+)
+
+func TestGroupLongPoll(t *testing.T) {
+	tests := []struct {
+		name   string
+		script func(lp *vktest.LongPoll)
+		// wantKey and wantTS are sent by the check after the script.
+		wantKey      string
+		wantTS       string
+		wantEvents   int
+		wantRefresh  bool
+		wantTSResets []string
+	}{
+		{
+			name:       "updates",
+			script:     func(lp *vktest.LongPoll) { lp.Push(groupUpdate(1), groupUpdate(2)) },
+			wantKey:    "key1",
+			wantTS:     "3",
+			wantEvents: 2,
+		},
+		{
+			name:         "failed 1",
+			script:       func(lp *vktest.LongPoll) { lp.Fail(1) },
+			wantKey:      "key1",
+			wantTS:       "11",
+			wantTSResets: []string{"11"},
+		},
+		{
+			name:        "failed 2",
+			script:      func(lp *vktest.LongPoll) { lp.Fail(2) },
+			wantKey:     "key2",
+			wantTS:      "1",
+			wantRefresh: true,
+		},
+		{
+			name:         "failed 3",
+			script:       func(lp *vktest.LongPoll) { lp.Fail(3) },
+			wantKey:      "key2",
+			wantTS:       "101",
+			wantRefresh:  true,
+			wantTSResets: []string{"101"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := vktest.NewServer()
+			defer srv.Close()
+			srv.GroupLP.Wait = 10 * time.Millisecond
+
+			vk := srv.Client("token")
+			var (
+				mu       sync.Mutex
+				events   int
+				refresh  bool
+				resets   []string
+				stopped  = make(chan error, 1)
+				connects int
+			)
+			vk.OnAnyGroupEvent(func(context.Context, *vkapi.GroupLPUpdates) {
+				mu.Lock()
+				events++
+				mu.Unlock()
+			})
+
+			lp := vkapi.NewGroupLongPoll(vk, 1)
+			lp.OnConnected = func(vkapi.LPServer) { connects++ }
+			lp.OnKeyRefreshed = func(vkapi.LPServer) { refresh = true }
+			lp.OnTSReset = func(ts string) { resets = append(resets, ts) }
+			lp.OnStopped = func(err error) { stopped <- err }
+			tt.script(srv.GroupLP)
+
+			runUntil(t, lp, func() bool { return srv.GroupLP.Checks() >= 2 })
+
+			reqs := srv.GroupLP.Requests()
+			if got := reqs[1]; got.Get("key") != tt.wantKey || got.Get("ts") != tt.wantTS {
+				t.Errorf("second check key %s ts %s, want key %s ts %s", got.Get("key"), got.Get("ts"), tt.wantKey, tt.wantTS)
+			}
+			mu.Lock()
+			if events != tt.wantEvents {
+				t.Errorf("got %d events, want %d", events, tt.wantEvents)
+			}
+			mu.Unlock()
+			if connects != 1 || refresh != tt.wantRefresh || len(resets) != len(tt.wantTSResets) {
+				t.Errorf("hooks: %d connects, refresh %v, ts resets %v", connects, refresh, resets)
+			}
+			for i := range tt.wantTSResets {
+				if resets[i] != tt.wantTSResets[i] {
+					t.Errorf("ts reset #%d = %s, want %s", i, resets[i], tt.wantTSResets[i])
+				}
+			}
+			if err := <-stopped; err != nil {
+				t.Errorf("OnStopped(%v), want nil", err)
+			}
+		})
+	}
+}
+
+func TestGroupLongPollResume(t *testing.T) {
+	srv := vktest.NewServer()
+	defer srv.Close()
+	srv.GroupLP.Wait = 10 * time.Millisecond
+
+	lp := vkapi.NewGroupLongPoll(srv.Client("token"), 1)
+	srv.GroupLP.Push(groupUpdate(1))
+	runUntil(t, lp, func() bool { return srv.GroupLP.Checks() >= 2 })
+
+	// A new server starts from an older ts, the received one must win.
+	srv.Handle("groups.getLongPollServer", vkapi.LPServer{Key: "key1", Server: srv.URL + "/lp/group", TS: "1"})
+	before := srv.GroupLP.Checks()
+	runUntil(t, lp, func() bool { return srv.GroupLP.Checks() > before })
+
+	if got := srv.GroupLP.Requests()[before].Get("ts"); got != "2" {
+		t.Errorf("resumed with ts %s, want 2", got)
+	}
+}
+
+func TestGroupLongPollWaitsForCallbacks(t *testing.T) {
+	srv := vktest.NewServer()
+	defer srv.Close()
+	srv.GroupLP.Wait = 10 * time.Millisecond
+
+	vk := srv.Client("token")
+	vk.Dispatcher = vkapi.NewDispatcher(1, 1)
+	defer vk.Dispatcher.Close()
+
+	started := make(chan struct{})
+	var done bool
+	vk.OnAnyGroupEvent(func(ctx context.Context, _ *vkapi.GroupLPUpdates) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		if ctx.Err() == nil {
+			done = true
+		}
+	})
+	srv.GroupLP.Push(groupUpdate(1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- vkapi.NewGroupLongPoll(vk, 1).Run(ctx) }()
+	<-started
+	cancel()
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if !done {
+		t.Error("Run returned before the callback finished or cancelled its ctx")
+	}
+}
+
+func TestGroupLongPollErrors(t *testing.T) {
+	t.Run("server error", func(t *testing.T) {
+		srv := vktest.NewServer()
+		defer srv.Close()
+		srv.HandleError("groups.getLongPollServer", vkapi.ErrAccessDenied)
+
+		err := vkapi.NewGroupLongPoll(srv.Client("token"), 1).Run(context.Background())
+		if !errors.Is(err, vkapi.ErrAccessDenied) {
+			t.Errorf("Run = %v, want %v", err, vkapi.ErrAccessDenied)
+		}
+	})
+
+	t.Run("already running", func(t *testing.T) {
+		srv := vktest.NewServer()
+		defer srv.Close()
+		srv.GroupLP.Wait = 10 * time.Millisecond
+
+		lp := vkapi.NewGroupLongPoll(srv.Client("token"), 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go lp.Run(ctx)
+		waitFor(t, func() bool { return srv.GroupLP.Checks() >= 1 })
+		if err := lp.Run(ctx); err != vkapi.ErrLongPollRunning {
+			t.Errorf("second Run = %v, want %v", err, vkapi.ErrLongPollRunning)
+		}
+	})
+}
+
+func groupUpdate(peerID int) vkapi.GroupLPUpdates {
+	obj, _ := json.Marshal(map[string]interface{}{
+		"message": map[string]int{"peer_id": peerID},
+	})
+	return vkapi.GroupLPUpdates{Type: vkapi.EventMessageNew, Object: obj, GroupID: 1}
+}
+
+// runUntil runs lp until cond holds and checks that Run returns nil.
+func runUntil(t *testing.T, lp *vkapi.GroupLongPoll, cond func() bool) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- lp.Run(ctx) }()
+	waitFor(t, cond)
+	cancel()
+	if err := <-errc; err != nil {
+		t.Fatalf("Run = %v, want nil", err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -1,445 +1,396 @@
 package vkapi
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
 	"strconv"
-	"strings"
 )
 
 const LastLPVersion = 3
 
+// User long poll event codes passed to MsgLPCallback.
+//
+// See https://vk.com/dev/using_longpoll
+const (
+	MsgLPCodeNewMessage = 4
+	MsgLPCodeUserTyping = 61
+)
+
 const (
 	ActivityTyping       = "typing"
 	ActivityAudioMessage = "audiomessage"
 )
 
 type MsgReq struct {
-	UserID          int64
-	RandomID        int64
-	PeerID          int64
-	Domain          string
-	ChatID          int64
-	UsersID         []int64
-	Message         string
-	Lat             float64
-	Long            float64
-	Attachments     []string
-	ReplyTo         int64
-	ForwardMessages []int64
-	StickerID       int
-	GroupID         int64
-	Keyboard        *Keyboard
-	Payload         string
-	DontParseLinks  bool
-	DisableMentions bool
-	Intent          string
+	UserID          int64     `vk:"user_id,omitempty"`
+	RandomID        int64     `vk:"random_id,omitempty"`
+	PeerID          int64     `vk:"peer_id,omitempty"`
+	Domain          string    `vk:"domain,omitempty"`
+	ChatID          int64     `vk:"chat_id,omitempty"`
+	UsersID         []int64   `vk:"user_ids,omitempty"`
+	Message         string    `vk:"message,omitempty"`
+	Lat             float64   `vk:"lat,omitempty"`
+	Long            float64   `vk:"long,omitempty"`
+	Attachments     []string  `vk:"attachment,omitempty"`
+	ReplyTo         int64     `vk:"reply_to,omitempty"`
+	ForwardMessages []int64   `vk:"forward_messages,omitempty"`
+	StickerID       int       `vk:"sticker_id,omitempty"`
+	GroupID         int64     `vk:"group_id,omitempty"`
+	Forward         *Forward  `vk:"forward,json,omitempty"`
+	Keyboard        *Keyboard `vk:"keyboard,json,omitempty"`
+	Template        *Template `vk:"template,json,omitempty"`
+	Payload         string    `vk:"payload,omitempty"`
+	DontParseLinks  bool      `vk:"dont_parse_links,omitempty"`
+	DisableMentions bool      `vk:"disable_mentions,omitempty"`
+	Intent          string    `vk:"intent,omitempty"`
 }
 
 func (MsgReq) Name() string {
 	return "messages.send"
 }
 
-func (m *MsgReq) Values() url.Values {
-	v := url.Values{}
-	if m.UserID != 0 {
-		v.Set("user_id", strconv.FormatInt(m.UserID, 10))
-	}
-
-	if m.RandomID != 0 {
-		v.Set("random_id", strconv.FormatInt(m.RandomID, 10))
-	}
-
-	if m.PeerID != 0 {
-		v.Set("peer_id", strconv.FormatInt(m.PeerID, 10))
-	}
-
-	if len(m.Domain) > 0 {
-		v.Set("domain", m.Domain)
-	}
-
-	if m.ChatID != 0 {
-		v.Set("chat_id", strconv.FormatInt(m.ChatID, 10))
-	}
-
-	if len(m.UsersID) > 0 {
-		v.Set("user_ids", sliceToStr(m.UsersID))
-	}
-
-	if len(m.Message) > 0 {
-		v.Set("message", m.Message)
-	}
-
-	if m.Lat != 0 {
-		v.Set("lat", fmt.Sprintf("%f", m.Lat))
-	}
-
-	if m.Long != 0 {
-		v.Set("long", fmt.Sprintf("%f", m.Long))
-	}
-
-	if len(m.Attachments) > 0 {
-		v.Set("attachment", strings.Join(m.Attachments, ","))
-	}
-
-	if m.ReplyTo > 0 {
-		v.Set("reply_to", strconv.FormatInt(m.ReplyTo, 10))
-	}
-
-	if len(m.ForwardMessages) > 0 {
-		v.Set("forward_messages", sliceToStr(m.ForwardMessages))
-	}
-
-	if m.StickerID > 0 {
-		v.Set("sticker_id", strconv.Itoa(m.StickerID))
-	}
-
-	if m.GroupID > 0 {
-		v.Set("group_id", strconv.FormatInt(m.GroupID, 10))
-	}
-
-	if m.Keyboard != nil {
-		k, err := json.Marshal(m.Keyboard)
-		if err != nil {
-			log.Print("marshalling error: %w", err)
-		}
-		v.Set("keyboard", string(k))
-	}
-
-	if len(m.Payload) > 0 {
-		v.Set("payload", m.Payload)
-	}
-
-	v.Set("dont_parse_links", strconv.FormatBool(m.DontParseLinks))
-	v.Set("disable_mentions", strconv.FormatBool(m.DisableMentions))
+// EncodeValues returns the params of m. It fails if Forward, Keyboard
+// or Template cannot be marshaled to JSON.
+func (m *MsgReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(m)
+}
 
-	if len(m.Intent) > 0 {
-		v.Set("Intent", m.Intent)
-	}
+// Values returns the params of m.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
+func (m *MsgReq) Values() url.Values {
+	values, _ := EncodeValues(m)
+	return values
+}
 
-	return v
+func (m MsgReq) Validate() error {
+	return validate(m.Name(),
+		requiredOneOf("peer_id, user_id, domain, chat_id, user_ids",
+			m.PeerID != 0 || m.UserID != 0 || m.Domain != "" || m.ChatID != 0 || len(m.UsersID) > 0),
+		requiredOneOf("message, attachment, sticker_id, forward_messages, forward",
+			m.Message != "" || len(m.Attachments) > 0 || m.StickerID != 0 || len(m.ForwardMessages) > 0 || m.Forward != nil),
+		maxLength("message", m.Message, MaxMessageLength),
+		maxItems("attachment", len(m.Attachments), MaxAttachments),
+		maxItems("user_ids", len(m.UsersID), 100),
+		maxItems("forward_messages", len(m.ForwardMessages), 100),
+	)
 }
 
 type MsgSetActivityReq struct {
-	UserID  int64
-	Type    string
-	PeerID  int64
-	GroupID int64
+	UserID  int64  `vk:"user_id,omitempty"`
+	Type    string `vk:"type,omitempty"`
+	PeerID  int64  `vk:"peer_id,omitempty"`
+	GroupID int64  `vk:"group_id,omitempty"`
 }
 
 func (MsgSetActivityReq) Name() string {
 	return "messages.setActivity"
 }
 
-func (m *MsgSetActivityReq) Values() url.Values {
-	v := url.Values{}
-	if m.UserID != 0 {
-		v.Set("user_id", strconv.FormatInt(m.UserID, 10))
-	}
-
-	if len(m.Type) > 0 {
-		v.Set("type", m.Type)
-	}
-
-	if m.PeerID != 0 {
-		v.Set("peer_id", strconv.FormatInt(m.PeerID, 10))
-	}
+// EncodeValues returns the params of m.
+func (m *MsgSetActivityReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(m)
+}
 
-	if m.GroupID > 0 {
-		v.Set("group_id", strconv.FormatInt(m.GroupID, 10))
-	}
+// Values returns the params of m.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
+func (m *MsgSetActivityReq) Values() url.Values {
+	values, _ := EncodeValues(m)
+	return values
+}
 
-	return v
+func (m MsgSetActivityReq) Validate() error {
+	return validate(m.Name(),
+		requiredOneOf("peer_id, user_id", m.PeerID != 0 || m.UserID != 0),
+		rule{
+			ok:     m.Type == "" || m.Type == ActivityTyping || m.Type == ActivityAudioMessage,
+			param:  "type",
+			reason: "must be " + ActivityTyping + " or " + ActivityAudioMessage,
+		},
+	)
 }
 
 type MsgEditReq struct {
-	PeerID              int
-	Message             string
-	MessageID           int
-	Lat                 float64
-	Long                float64
-	Attachment          []string
-	GroupID             int
-	KeepForwardMessages bool
-	KeepSnippets        bool
-	DontParseLinks      bool
+	PeerID              int       `vk:"peer_id,omitempty"`
+	Message             string    `vk:"message,omitempty"`
+	MessageID           int       `vk:"message_id,omitempty"`
+	Lat                 float64   `vk:"lat,omitempty"`
+	Long                float64   `vk:"long,omitempty"`
+	Attachment          []string  `vk:"attachment,omitempty"`
+	GroupID             int       `vk:"group_id,omitempty"`
+	KeepForwardMessages bool      `vk:"keep_forward_messages,omitempty"`
+	KeepSnippets        bool      `vk:"keep_snippets,omitempty"`
+	DontParseLinks      bool      `vk:"dont_parse_links,omitempty"`
+	Keyboard            *Keyboard `vk:"keyboard,json,omitempty"`
+	Template            *Template `vk:"template,json,omitempty"`
 }
 
 func (MsgEditReq) Name() string {
 	return "messages.edit"
 }
 
-func (m *MsgEditReq) Values() url.Values {
-	v := url.Values{}
-	if m.PeerID != 0 {
-		v.Set("peer_id", strconv.Itoa(m.PeerID))
-	}
-
-	if len(m.Message) > 0 {
-		v.Set("message", m.Message)
-	}
-
-	if m.MessageID != 0 {
-		v.Set("message_id", strconv.Itoa(m.MessageID))
-	}
-
-	if m.Lat != 0 {
-		v.Set("lat", fmt.Sprintf("%f", m.Lat))
-	}
-
-	if m.Long != 0 {
-		v.Set("long", fmt.Sprintf("%f", m.Long))
-	}
-
-	if len(m.Attachment) > 0 {
-		v.Set("attachment", strings.Join(m.Attachment, ","))
-	}
-
-	if m.GroupID > 0 {
-		v.Set("group_id", strconv.Itoa(m.GroupID))
-	}
+// EncodeValues returns the params of m. It fails if Keyboard or
+// Template cannot be marshaled to JSON.
+func (m *MsgEditReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(m)
+}
 
-	v.Set("keep_forward_messages", strconv.FormatBool(m.KeepForwardMessages))
-	v.Set("keep_snippets", strconv.FormatBool(m.KeepSnippets))
-	v.Set("dont_parse_links", strconv.FormatBool(m.DontParseLinks))
+// Values returns the params of m.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
+func (m *MsgEditReq) Values() url.Values {
+	values, _ := EncodeValues(m)
+	return values
+}
 
-	return v
+func (m MsgEditReq) Validate() error {
+	return validate(m.Name(),
+		required("peer_id", m.PeerID != 0),
+		required("message_id", m.MessageID != 0),
+		maxLength("message", m.Message, MaxMessageLength),
+		maxItems("attachment", len(m.Attachment), MaxAttachments),
+	)
 }
 
 type MsgDeleteReq struct {
-	MessageIDs   []int64
-	Spam         bool
-	GroupID      int
-	DeleteForAll bool
+	MessageIDs   []int64 `vk:"message_ids,omitempty"`
+	Spam         bool    `vk:"spam,omitempty"`
+	GroupID      int     `vk:"group_id,omitempty"`
+	DeleteForAll bool    `vk:"delete_for_all,omitempty"`
 }
 
 func (MsgDeleteReq) Name() string {
 	return "messages.delete"
 }
 
-func (m *MsgDeleteReq) Values() url.Values {
-	v := url.Values{}
-
-	if len(m.MessageIDs) > 0 {
-		v.Set("message_ids", sliceToStr(m.MessageIDs))
-	}
-
-	v.Set("spam", strconv.FormatBool(m.Spam))
-
-	if m.GroupID != 0 {
-		v.Set("group_id", strconv.Itoa(m.GroupID))
-	}
+// EncodeValues returns the params of m.
+func (m *MsgDeleteReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(m)
+}
 
-	v.Set("delete_for_all", strconv.Itoa(btoi(m.DeleteForAll)))
+// Values returns the params of m.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
+func (m *MsgDeleteReq) Values() url.Values {
+	values, _ := EncodeValues(m)
+	return values
+}
 
-	return v
+func (m MsgDeleteReq) Validate() error {
+	return validate(m.Name(),
+		required("message_ids", len(m.MessageIDs) > 0),
+		maxItems("message_ids", len(m.MessageIDs), 1000),
+	)
 }
 
 type GetByConversationMessageIDReq struct {
-	PeerID                 []int64
-	ConversationMessageIDs []int64
-	Extended               bool
-	Fields                 []string
-	GroupID                int
+	PeerID                 []int64  `vk:"peer_id,omitempty"`
+	ConversationMessageIDs []int64  `vk:"conversation_message_ids,omitempty"`
+	Extended               bool     `vk:"extended,omitempty"`
+	Fields                 []string `vk:"fields,omitempty"`
+	GroupID                int      `vk:"group_id,omitempty"`
 }
 
 func (GetByConversationMessageIDReq) Name() string {
 	return "messages.getByConversationMessageId"
 }
 
-func (g *GetByConversationMessageIDReq) Values() url.Values {
-	v := url.Values{}
-	if len(g.PeerID) > 0 {
-		v.Set("peer_id", sliceToStr(g.PeerID))
-	}
-
-	if len(g.PeerID) > 0 {
-		v.Set("conversation_message_ids", sliceToStr(g.ConversationMessageIDs))
-	}
-
-	v.Set("extended", strconv.Itoa(btoi(g.Extended)))
+// EncodeValues returns the params of g.
+func (g *GetByConversationMessageIDReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(g)
+}
 
-	if len(g.Fields) > 0 {
-		v.Set("fields", strings.Join(g.Fields, ","))
-	}
+// Values returns the params of g.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
+func (g *GetByConversationMessageIDReq) Values() url.Values {
+	values, _ := EncodeValues(g)
+	return values
+}
 
-	return v
+func (g GetByConversationMessageIDReq) Validate() error {
+	return validate(g.Name(),
+		required("peer_id", len(g.PeerID) > 0),
+		required("conversation_message_ids", len(g.ConversationMessageIDs) > 0),
+		maxItems("conversation_message_ids", len(g.ConversationMessageIDs), 100),
+	)
 }
 
 type MsgGetLPServerReq struct {
-	NeedPTS   bool
-	GroupID   int
-	LPVersion int
+	NeedPTS   bool `vk:"need_pts,omitempty"`
+	GroupID   int  `vk:"group_id,omitempty"`
+	LPVersion int  `vk:"lp_version,omitempty"`
 }
 
 func (MsgGetLPServerReq) Name() string {
 	return "messages.getLongPollServer"
 }
 
-func (m *MsgGetLPServerReq) Values() url.Values {
-	v := url.Values{}
-	v.Set("need_pts", strconv.Itoa(btoi(m.NeedPTS)))
+// EncodeValues returns the params of m.
+func (m *MsgGetLPServerReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(m)
+}
 
-	if m.GroupID > 0 {
-		v.Set("group_id", strconv.Itoa(m.GroupID))
-	}
+// Values returns the params of m.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
+func (m *MsgGetLPServerReq) Values() url.Values {
+	values, _ := EncodeValues(m)
+	return values
+}
 
-	v.Set("lp_version", strconv.Itoa(m.LPVersion))
-	return v
+func (m MsgGetLPServerReq) Validate() error {
+	return validate(m.Name(),
+		inRange("lp_version", m.LPVersion, 0, LastLPVersion),
+	)
 }
 
 type MsgMarkAsReadReq struct {
-	MessageIDs             []int64
-	PeerID                 int64
-	StartMessageID         int64
-	GroupID                int64
-	MarkConversationAsRead bool
+	MessageIDs             []int64 `vk:"message_ids,omitempty"`
+	PeerID                 int64   `vk:"peer_id,omitempty"`
+	StartMessageID         int64   `vk:"start_message_id,omitempty"`
+	GroupID                int64   `vk:"group_id,omitempty"`
+	MarkConversationAsRead bool    `vk:"mark_conversation_as_read,omitempty"`
 }
 
 func (MsgMarkAsReadReq) Name() string {
 	return "messages.markAsRead"
 }
 
+// EncodeValues returns the params of r.
+func (r *MsgMarkAsReadReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(r)
+}
+
+// Values returns the params of r.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
 func (r *MsgMarkAsReadReq) Values() url.Values {
-	v := url.Values{}
-	if len(r.MessageIDs) > 0 {
-		v.Set("message_ids", sliceToStr(r.MessageIDs))
-	}
-	if r.PeerID != 0 {
-		v.Set("peer_id", strconv.FormatInt(r.PeerID, 10))
-	}
-	if r.StartMessageID != 0 {
-		v.Set("start_message_id", strconv.FormatInt(r.StartMessageID, 10))
-	}
-	if r.GroupID != 0 {
-		v.Set("group_id", strconv.FormatInt(r.GroupID, 10))
-	}
-	v.Set("mark_conversation_as_read", strconv.FormatBool(r.MarkConversationAsRead))
-	return v
+	values, _ := EncodeValues(r)
+	return values
+}
+
+func (r MsgMarkAsReadReq) Validate() error {
+	return validate(r.Name(),
+		requiredOneOf("message_ids, peer_id", len(r.MessageIDs) > 0 || r.PeerID != 0),
+	)
 }
 
 // MsgSend sends a message.
 //
 // See https://vk.com/dev/messages.send
 func (vk *VkAPI) MsgSend(v *MsgReq) ([]NewMessageResp, error) {
-	resp, err := vk.MakeRequest(v.Name(), v.Values())
-	if err != nil {
-		return nil, err
-	}
+	return vk.MsgSendContext(context.Background(), v)
+}
 
+// MsgSendContext is like MsgSend but uses ctx for the request.
+func (vk *VkAPI) MsgSendContext(ctx context.Context, v *MsgReq) ([]NewMessageResp, error) {
 	if len(v.UsersID) > 0 {
-		var m []NewMessageResp
-		if err := json.Unmarshal(resp.Response, &m); err != nil {
-			return nil, err
-		}
-		return m, nil
+		return Call[[]NewMessageResp](ctx, vk, v)
 	}
 
-	m, err := resp.Response.MarshalJSON()
+	id, err := Call[int](ctx, vk, v)
 	if err != nil {
 		return nil, err
 	}
-	buf := bytes.NewBuffer(m)
-	id, err := binary.ReadVarint(buf)
-	if err != nil {
-		return nil, err
-	}
-	return []NewMessageResp{{MessageID: int(id)}}, nil
-
+	return []NewMessageResp{{MessageID: id}}, nil
 }
 
 // MsgSetActivity changes the status of a user as typing in a conversation.
 //
 // See https://vk.com/dev/messages.setActivity
 func (vk *VkAPI) MsgSetActivity(v *MsgSetActivityReq) error {
-	_, err := vk.MakeRequest(v.Name(), v.Values())
-	if err != nil {
-		return err
-	}
-	return nil
+	return vk.MsgSetActivityContext(context.Background(), v)
+}
+
+// MsgSetActivityContext is like MsgSetActivity but uses ctx for the request.
+func (vk *VkAPI) MsgSetActivityContext(ctx context.Context, v *MsgSetActivityReq) error {
+	_, err := Call[int](ctx, vk, v)
+	return err
 }
 
 // MsgEdit edits the message.
 //
 // See https://vk.com/dev/messages.edit
 func (vk *VkAPI) MsgEdit(v *MsgEditReq) error {
-	_, err := vk.MakeRequest(v.Name(), v.Values())
-	if err != nil {
-		return err
-	}
-	return nil
+	return vk.MsgEditContext(context.Background(), v)
+}
+
+// MsgEditContext is like MsgEdit but uses ctx for the request.
+func (vk *VkAPI) MsgEditContext(ctx context.Context, v *MsgEditReq) error {
+	_, err := Call[int](ctx, vk, v)
+	return err
 }
 
 // MsgDelete deletes one or more messages.
 //
 // See https://vk.com/dev/messages.delete
 func (vk *VkAPI) MsgDelete(v *MsgDeleteReq) error {
-	_, err := vk.MakeRequest(v.Name(), v.Values())
-	if err != nil {
-		return err
-	}
-	return nil
+	return vk.MsgDeleteContext(context.Background(), v)
+}
+
+// MsgDeleteContext is like MsgDelete but uses ctx for the request.
+func (vk *VkAPI) MsgDeleteContext(ctx context.Context, v *MsgDeleteReq) error {
+	_, err := Call[json.RawMessage](ctx, vk, v)
+	return err
 }
 
 func (vk *VkAPI) MsgMarkAsRead(v *MsgMarkAsReadReq) error {
-	_, err := vk.MakeRequest(v.Name(), v.Values())
-	if err != nil {
-		return err
-	}
-	return nil
+	return vk.MsgMarkAsReadContext(context.Background(), v)
+}
+
+// MsgMarkAsReadContext is like MsgMarkAsRead but uses ctx for the request.
+func (vk *VkAPI) MsgMarkAsReadContext(ctx context.Context, v *MsgMarkAsReadReq) error {
+	_, err := Call[int](ctx, vk, v)
+	return err
 }
 
 // MsgGetByConversationMessageID returns messages by their IDs.
 //
 // See https://vk.com/dev/messages.getByConversationMessageId
 func (vk *VkAPI) MsgGetByConversationMessageID(v *GetByConversationMessageIDReq) (*MessagesWithCount, error) {
-	resp, err := vk.MakeRequest(v.Name(), v.Values())
-	if err != nil {
-		return nil, err
-	}
-
-	var m MessagesWithCount
-	if err := json.Unmarshal(resp.Response, &m); err != nil {
-		return nil, err
-	}
+	return vk.MsgGetByConversationMessageIDContext(context.Background(), v)
+}
 
-	return &m, nil
+// MsgGetByConversationMessageIDContext is like MsgGetByConversationMessageID but uses ctx for the request.
+func (vk *VkAPI) MsgGetByConversationMessageIDContext(ctx context.Context, v *GetByConversationMessageIDReq) (*MessagesWithCount, error) {
+	return Call[*MessagesWithCount](ctx, vk, v)
 }
 
 // Edits the message.
 //
 // See https://vk.com/dev/messages.edit
 func (vk *VkAPI) MsgGetLPServer(v *MsgGetLPServerReq) (*MsgLPServer, error) {
-	resp, err := vk.MakeRequest(v.Name(), v.Values())
-	if err != nil {
-		return nil, err
-	}
+	return vk.MsgGetLPServerContext(context.Background(), v)
+}
 
-	var apiResp MsgLPServer
-	if err := json.Unmarshal(resp.Response, &apiResp); err != nil {
-		return nil, err
-	}
-	return &apiResp, nil
+// MsgGetLPServerContext is like MsgGetLPServer but uses ctx for the request.
+func (vk *VkAPI) MsgGetLPServerContext(ctx context.Context, v *MsgGetLPServerReq) (*MsgLPServer, error) {
+	return Call[*MsgLPServer](ctx, vk, v)
 }
 
 func (vk *VkAPI) MsgLPServ(groupID, mode int) error {
-	return vk.msgLongPoll(context.Background(), groupID, LastLPVersion, mode)
+	return vk.MsgLPServContext(context.Background(), groupID, mode)
+}
+
+// MsgLPServContext is like MsgLPServ but stops polling when ctx is done.
+// It returns ErrDispatcherClosed if vk.Dispatcher is closed meanwhile.
+func (vk *VkAPI) MsgLPServContext(ctx context.Context, groupID, mode int) error {
+	return vk.msgLongPoll(ctx, groupID, LastLPVersion, mode)
 }
 
 func (vk *VkAPI) msgLongPoll(ctx context.Context, groupID, LPVersion, mode int) error {
-	server, err := vk.MsgGetLPServer(&MsgGetLPServerReq{
+	log := vk.logger()
+	server, err := vk.MsgGetLPServerContext(ctx, &MsgGetLPServerReq{
 		GroupID:   groupID,
 		LPVersion: LPVersion,
 	})
 	if err != nil {
-		log.Print("Get message lp server is failed: %w", err)
+		log.Error("get message long poll server failed", "group_id", groupID, "error", err)
 		return err
 	}
 
@@ -450,38 +401,47 @@ func (vk *VkAPI) msgLongPoll(ctx context.Context, groupID, LPVersion, mode int)
 		default:
 		}
 
-		serverURL := fmt.Sprintf(
-			"https://%s?act=a_check&key=%s&ts=%d&wait=25&mode=%d&version=%d",
-			server.Server, server.Key, server.TS, mode, LPVersion)
+		serverURL := vk.lpURL(server.Server, url.Values{
+			"act":     {"a_check"},
+			"key":     {server.Key},
+			"ts":      {strconv.Itoa(server.TS)},
+			"wait":    {"25"},
+			"mode":    {strconv.Itoa(mode)},
+			"version": {strconv.Itoa(LPVersion)},
+		})
 
-		req, err := http.NewRequest(http.MethodGet, serverURL, nil)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, serverURL, nil)
 		if err != nil {
-			log.Print("Message lp server connection failed: %w", err)
+			log.Error("message long poll request failed", "group_id", groupID, "ts", server.TS, "error", err)
 			continue
 		}
 
 		resp, err := vk.Client.Do(req)
 		if err != nil {
-			log.Print("Message lp request connection failed: %w", err)
+			log.Error("message long poll connection failed", "group_id", groupID, "ts", server.TS, "error", err)
 			continue
 		}
 
 		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
 		if err != nil {
-			fmt.Println(err)
+			log.Error("message long poll body read failed", "group_id", groupID, "ts", server.TS, "error", err)
+			continue
 		}
 
 		var e MsgLPEvent
 		if err := json.Unmarshal(body, &e); err != nil {
-			fmt.Println(err)
+			log.Error("message long poll event decode failed", "group_id", groupID, "ts", server.TS, "error", err)
+			continue
 		}
 
 		switch true {
 		case e.Failed == 0:
 			for _, u := range e.Updates {
 				code := u[0].(float64)
+				var err error
 				switch code {
-				case 4:
+				case MsgLPCodeNewMessage:
 					m := MsgLPNewMessage{
 						MessageID:  int(u[1].(float64)),
 						Flags:      int(u[2].(float64)),
@@ -503,22 +463,30 @@ func (vk *VkAPI) msgLongPoll(ctx context.Context, groupID, LPVersion, mode int)
 						m.Type = "message_reply"
 					}
 
-					vk.handleMsgLPCallback(4, m)
-				case 61:
+					err = vk.dispatchMsgEvent(ctx, MsgLPCodeNewMessage, m.PeerID, m)
+				case MsgLPCodeUserTyping:
 					t := MsgLPUserTyping{UserID: int(u[1].(float64))}
-					vk.handleMsgLPCallback(61, t)
+					err = vk.dispatchMsgEvent(ctx, MsgLPCodeUserTyping, t.UserID, t)
+				}
+				if err != nil {
+					if ctx.Err() != nil {
+						return nil
+					}
+					return err
 				}
 			}
 			server.TS = e.TS
 		case e.Failed == 1:
+			log.Debug("message long poll history outdated", "group_id", groupID, "ts", server.TS, "failed", e.Failed)
 			server.TS = e.TS
 		case e.Failed == 2 || e.Failed == 3:
-			newServer, err := vk.MsgGetLPServer(&MsgGetLPServerReq{
+			log.Info("message long poll key expired", "group_id", groupID, "ts", server.TS, "failed", e.Failed)
+			newServer, err := vk.MsgGetLPServerContext(ctx, &MsgGetLPServerReq{
 				GroupID:   groupID,
 				LPVersion: LPVersion,
 			})
 			if err != nil {
-				log.Print("Get message lp server is failed: %w", err)
+				log.Error("get message long poll server failed", "group_id", groupID, "failed", e.Failed, "error", err)
 				continue
 			}
 
@@ -531,17 +499,32 @@ func (vk *VkAPI) msgLongPoll(ctx context.Context, groupID, LPVersion, mode int)
 				server.TS = newServer.TS
 			}
 		}
-
-		if resp != nil {
-			resp.Body.Close()
-		}
 	}
 }
 
-func (vk *VkAPI) handleMsgLPCallback(code int, event interface{}) {
-	_, exists := vk.msgLPSubs.events[code]
+// MsgLPCallback registers f for user long poll events with the given
+// code, e.g. MsgLPCodeNewMessage.
+func (vk *VkAPI) MsgLPCallback(code int, f func(event interface{})) Unsubscribe {
+	return vk.msgEvents.add(code, func(_ context.Context, _ int, event interface{}) {
+		f(event)
+	})
+}
 
-	if exists {
-		vk.msgLPSubs.events[code](event)
-	}
+// OnAnyMsgEvent registers f for user long poll events of every code.
+// It runs after the callbacks of the event code.
+func (vk *VkAPI) OnAnyMsgEvent(f func(ctx context.Context, code int, event interface{})) Unsubscribe {
+	return vk.msgEvents.addAny(f)
+}
+
+// dispatchMsgEvent passes event to the callbacks in order with other
+// events of peerID.
+func (vk *VkAPI) dispatchMsgEvent(ctx context.Context, code, peerID int, event interface{}) error {
+	hctx := context.WithoutCancel(ctx)
+	return vk.dispatchEvent(ctx, strconv.Itoa(peerID), func() {
+		vk.handleMsgLPCallback(hctx, code, event)
+	})
+}
+
+func (vk *VkAPI) handleMsgLPCallback(ctx context.Context, code int, event interface{}) {
+	vk.msgEvents.dispatch(ctx, code, event, vk.reportEventError)
 }
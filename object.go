@@ -144,6 +144,17 @@ type Comments struct {
 	CanOpen       bool `json:"can_open"`
 }
 
+type Comment struct {
+	ID             int          `json:"id"`
+	FromID         int          `json:"from_id"`
+	Date           int64        `json:"date"`
+	Text           string       `json:"text"`
+	ReplyToUser    int          `json:"reply_to_user"`
+	ReplyToComment int          `json:"reply_to_comment"`
+	Attachments    []Attachment `json:"attachments"`
+	ParentsStack   []int        `json:"parents_stack"`
+}
+
 type Likes struct {
 	Count      int `json:"count"`
 	UserLikes  int `json:"user_likes"`
@@ -213,10 +224,7 @@ type GroupLPUpdates struct {
 	Type    string          `json:"type"`
 	Object  json.RawMessage `json:"object"`
 	GroupID int             `json:"group_id"`
-}
-
-type GroupLPSubs struct {
-	events map[string]func(m *GroupLPUpdates)
+	EventID string          `json:"event_id"`
 }
 
 type MsgLPUserTyping struct {
@@ -233,10 +241,6 @@ type MsgLPNewMessage struct {
 	Attachment map[string]string
 }
 
-type MsgLPSubs struct {
-	events map[int]func(m interface{})
-}
-
 type Error struct {
 	Code        int
 	Description string
@@ -253,6 +257,33 @@ type Keyboard struct {
 	Inline  bool       `json:"inline"`
 }
 
+// Forward describes messages to forward or reply to.
+//
+// See https://vk.com/dev/messages.send
+type Forward struct {
+	OwnerID                int64   `json:"owner_id,omitempty"`
+	PeerID                 int64   `json:"peer_id"`
+	ConversationMessageIDs []int64 `json:"conversation_message_ids,omitempty"`
+	MessageIDs             []int64 `json:"message_ids,omitempty"`
+	IsReply                bool    `json:"is_reply,omitempty"`
+}
+
+// Template is a message template such as a carousel.
+//
+// See https://vk.com/dev/bot_docs_templates
+type Template struct {
+	Type     string            `json:"type"`
+	Elements []TemplateElement `json:"elements"`
+}
+
+type TemplateElement struct {
+	Title       string                 `json:"title,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	PhotoID     string                 `json:"photo_id,omitempty"`
+	Buttons     []Button               `json:"buttons"`
+	Action      map[string]interface{} `json:"action,omitempty"`
+}
+
 type User struct {
 	ID              int    `json:"id"`
 	FirstName       string `json:"first_name"`
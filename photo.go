@@ -2,7 +2,7 @@ package vkapi
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -22,112 +22,126 @@ type UploadServer struct {
 }
 
 type GetWallUploadServerReq struct {
-	GroupID int64
+	GroupID int64 `vk:"group_id,omitempty"`
 }
 
 func (GetWallUploadServerReq) Name() string {
 	return "photos.getWallUploadServer"
 }
 
+// EncodeValues returns the params of r.
+func (r GetWallUploadServerReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(r)
+}
+
+// Values returns the params of r.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
+func (r GetWallUploadServerReq) Values() url.Values {
+	values, _ := EncodeValues(r)
+	return values
+}
+
 type GetWallUploadServerResp struct {
 	UploadURL string `json:"upload_url"`
 	AlbumID   int    `json:"album_id"`
 	UserID    int64  `json:"user_id"`
 }
 
-func (r GetWallUploadServerReq) Values() url.Values {
-	v := url.Values{}
-	if r.GroupID > 0 {
-		v.Set("group_id", strconv.FormatInt(r.GroupID, 10))
-	}
-	return v
+func (vk *VkAPI) GetWallUploadServer(r *GetWallUploadServerReq) (*GetWallUploadServerResp, error) {
+	return vk.GetWallUploadServerContext(context.Background(), r)
 }
 
-func (vk *VkAPI) GetWallUploadServer(r *GetWallUploadServerReq) (*GetWallUploadServerResp, error) {
-	resp, err := vk.MakeRequest(r.Name(), r.Values())
-	if err != nil {
-		return nil, err
-	}
-	var w GetWallUploadServerResp
-	if err := json.Unmarshal(resp.Response, &w); err != nil {
-		return nil, err
-	}
-	return &w, nil
+// GetWallUploadServerContext is like GetWallUploadServer but uses ctx for the request.
+func (vk *VkAPI) GetWallUploadServerContext(ctx context.Context, r *GetWallUploadServerReq) (*GetWallUploadServerResp, error) {
+	return Call[*GetWallUploadServerResp](ctx, vk, r)
 }
 
 type SaveWallPhotoReq struct {
-	UserID    int
-	GroupID   int64
-	Photo     string
-	Server    int
-	Hash      string
-	Longitude float64
-	Latitude  float64
-	Caption   string
+	UserID    int     `vk:"user_id,omitempty"`
+	GroupID   int64   `vk:"group_id,omitempty"`
+	Photo     string  `vk:"photo"`
+	Server    int     `vk:"server"`
+	Hash      string  `vk:"hash"`
+	Longitude float64 `vk:"longitude,omitempty"`
+	Latitude  float64 `vk:"latitude,omitempty"`
+	Caption   string  `vk:"caption,omitempty"`
 }
 
 func (SaveWallPhotoReq) Name() string {
 	return "photos.saveWallPhoto"
 }
 
-func (s SaveWallPhotoReq) Values() url.Values {
-	v := url.Values{}
-	if s.UserID > 0 {
-		v.Set("user_id", strconv.Itoa(s.UserID))
-	}
-	if s.GroupID > 0 {
-		v.Set("group_id", strconv.FormatInt(s.GroupID, 10))
-	}
-	v.Set("photo", s.Photo)
-	v.Set("server", strconv.Itoa(s.Server))
-	v.Set("hash", s.Hash)
-
-	if s.Longitude > 0 {
-		v.Set("longitude", strconv.FormatFloat(s.Longitude, 'f', -1, 64))
-	}
+// EncodeValues returns the params of s.
+func (s SaveWallPhotoReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(s)
+}
 
-	if s.Latitude > 0 {
-		v.Set("latitude", strconv.FormatFloat(s.Latitude, 'f', -1, 64))
-	}
+// Values returns the params of s.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
+func (s SaveWallPhotoReq) Values() url.Values {
+	values, _ := EncodeValues(s)
+	return values
+}
 
-	if len(s.Caption) > 0 {
-		v.Set("caption", s.Caption)
-	}
-	return v
+func (s SaveWallPhotoReq) Validate() error {
+	return validate(s.Name(),
+		required("photo", s.Photo != ""),
+		required("hash", s.Hash != ""),
+		maxLength("caption", s.Caption, 2048),
+	)
 }
 
 type GetMessagesUploadServerReq struct {
-	PeerID int64
+	PeerID int64 `vk:"peer_id,omitempty"`
 }
 
 func (GetMessagesUploadServerReq) Name() string {
 	return "photos.getMessagesUploadServer"
 }
 
+// EncodeValues returns the params of r.
+func (r GetMessagesUploadServerReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(r)
+}
+
+// Values returns the params of r.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
 func (r GetMessagesUploadServerReq) Values() url.Values {
-	v := url.Values{}
-	if r.PeerID > 0 {
-		v.Set("peer_id", strconv.FormatInt(r.PeerID, 10))
-	}
-	return v
+	values, _ := EncodeValues(r)
+	return values
 }
 
 type SaveMessagesPhotoReq struct {
-	Photo  string
-	Server int
-	Hash   string
+	Photo  string `vk:"photo"`
+	Server int    `vk:"server"`
+	Hash   string `vk:"hash"`
 }
 
 func (SaveMessagesPhotoReq) Name() string {
 	return "photos.saveMessagesPhoto"
 }
 
+// EncodeValues returns the params of r.
+func (r SaveMessagesPhotoReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(r)
+}
+
+// Values returns the params of r.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
 func (r SaveMessagesPhotoReq) Values() url.Values {
-	v := url.Values{}
-	v.Set("photo", r.Photo)
-	v.Set("server", strconv.Itoa(r.Server))
-	v.Set("hash", r.Hash)
-	return v
+	values, _ := EncodeValues(r)
+	return values
+}
+
+func (r SaveMessagesPhotoReq) Validate() error {
+	return validate(r.Name(),
+		required("photo", r.Photo != ""),
+		required("hash", r.Hash != ""),
+	)
 }
 
 type GetMessagesUploadServerResp struct {
@@ -137,44 +151,38 @@ type GetMessagesUploadServerResp struct {
 }
 
 func (vk *VkAPI) GetMessagesUploadServer(r *GetMessagesUploadServerReq) (*GetMessagesUploadServerResp, error) {
-	resp, err := vk.MakeRequest(r.Name(), r.Values())
-	if err != nil {
-		return nil, err
-	}
-	var w GetMessagesUploadServerResp
-	if err := json.Unmarshal(resp.Response, &w); err != nil {
-		return nil, err
-	}
-	return &w, nil
+	return vk.GetMessagesUploadServerContext(context.Background(), r)
+}
+
+// GetMessagesUploadServerContext is like GetMessagesUploadServer but uses ctx for the request.
+func (vk *VkAPI) GetMessagesUploadServerContext(ctx context.Context, r *GetMessagesUploadServerReq) (*GetMessagesUploadServerResp, error) {
+	return Call[*GetMessagesUploadServerResp](ctx, vk, r)
 }
 
 func (vk *VkAPI) SaveWallPhoto(r *SaveWallPhotoReq) ([]Photo, error) {
-	resp, err := vk.MakeRequest(r.Name(), r.Values())
-	if err != nil {
-		return nil, err
-	}
+	return vk.SaveWallPhotoContext(context.Background(), r)
+}
 
-	var p []Photo
-	if err := json.Unmarshal(resp.Response, &p); err != nil {
-		return nil, err
-	}
-	return p, nil
+// SaveWallPhotoContext is like SaveWallPhoto but uses ctx for the request.
+func (vk *VkAPI) SaveWallPhotoContext(ctx context.Context, r *SaveWallPhotoReq) ([]Photo, error) {
+	return Call[[]Photo](ctx, vk, r)
 }
 
 func (vk *VkAPI) SaveMessagesPhoto(r *SaveMessagesPhotoReq) ([]Photo, error) {
-	resp, err := vk.MakeRequest(r.Name(), r.Values())
-	if err != nil {
-		return nil, err
-	}
+	return vk.SaveMessagesPhotoContext(context.Background(), r)
+}
 
-	var p []Photo
-	if err := json.Unmarshal(resp.Response, &p); err != nil {
-		return nil, err
-	}
-	return p, nil
+// SaveMessagesPhotoContext is like SaveMessagesPhoto but uses ctx for the request.
+func (vk *VkAPI) SaveMessagesPhotoContext(ctx context.Context, r *SaveMessagesPhotoReq) ([]Photo, error) {
+	return Call[[]Photo](ctx, vk, r)
 }
 
 func MakeUploadPhotoRequest(uploadURL string, files []File) (*http.Request, error) {
+	return MakeUploadPhotoRequestContext(context.Background(), uploadURL, files)
+}
+
+// MakeUploadPhotoRequestContext is like MakeUploadPhotoRequest but binds ctx to the request.
+func MakeUploadPhotoRequestContext(ctx context.Context, uploadURL string, files []File) (*http.Request, error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
@@ -192,7 +200,7 @@ func MakeUploadPhotoRequest(uploadURL string, files []File) (*http.Request, erro
 		return nil, err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, uploadURL, body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, body)
 	if err != nil {
 		return nil, err
 	}
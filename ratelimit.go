@@ -0,0 +1,82 @@
+package vkapi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Request limits per access token type.
+//
+// See https://vk.com/dev/api_requests
+const (
+	UserTokenRPS    = 3
+	GroupTokenRPS   = 20
+	ServiceTokenRPS = 3
+)
+
+// RateLimiter blocks until the next request may be sent.
+// Implementations must be safe for concurrent use.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// Limiter allows at most N requests within any one second window.
+type Limiter struct {
+	mu     sync.Mutex
+	window time.Duration
+	slots  []time.Time
+	next   int
+}
+
+// NewLimiter returns a limiter that allows rps requests per second.
+func NewLimiter(rps int) *Limiter {
+	if rps < 1 {
+		rps = 1
+	}
+	return &Limiter{
+		window: time.Second,
+		slots:  make([]time.Time, rps),
+	}
+}
+
+// NewUserLimiter returns a limiter for user access tokens.
+func NewUserLimiter() *Limiter {
+	return NewLimiter(UserTokenRPS)
+}
+
+// NewGroupLimiter returns a limiter for community access tokens.
+func NewGroupLimiter() *Limiter {
+	return NewLimiter(GroupTokenRPS)
+}
+
+// NewServiceLimiter returns a limiter for service access tokens.
+func NewServiceLimiter() *Limiter {
+	return NewLimiter(ServiceTokenRPS)
+}
+
+// Wait sleeps until a request may be sent and takes its slot.
+// Nothing is reserved while sleeping, so a waiter whose ctx is done
+// returns ctx.Err() without using up the rate of the others.
+func (l *Limiter) Wait(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		l.mu.Lock()
+		now := time.Now()
+		free := l.slots[l.next].Add(l.window)
+		if !free.After(now) {
+			l.slots[l.next] = now
+			l.next = (l.next + 1) % len(l.slots)
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		if err := sleep(ctx, free.Sub(now)); err != nil {
+			return err
+		}
+	}
+}
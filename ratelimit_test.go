@@ -0,0 +1,76 @@
+package vkapi
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(rps int, window time.Duration) *Limiter {
+	l := NewLimiter(rps)
+	l.window = window
+	return l
+}
+
+func TestLimiterWait(t *testing.T) {
+	const window = 100 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		rps      int
+		calls    int
+		min, max time.Duration
+	}{
+		{"within limit", 3, 3, 0, window / 2},
+		{"one over limit", 3, 4, window, 2 * window},
+		{"two windows", 2, 5, 2 * window, 3 * window},
+		{"rps below one", 0, 2, window, 2 * window},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLimiter(tt.rps, window)
+			start := time.Now()
+			for i := 0; i < tt.calls; i++ {
+				if err := l.Wait(context.Background()); err != nil {
+					t.Fatalf("Wait: %v", err)
+				}
+			}
+			if d := time.Since(start); d < tt.min || d > tt.max {
+				t.Errorf("%d calls took %v, want between %v and %v", tt.calls, d, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestLimiterWaitCancelled(t *testing.T) {
+	const window = 200 * time.Millisecond
+	l := newTestLimiter(1, window)
+
+	start := time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), window/4)
+	defer cancel()
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Wait = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// The cancelled waiter must not have used the next slot.
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if d := time.Since(start); d > window+window/2 {
+		t.Errorf("Wait after a cancelled waiter took %v, want about %v", d, window)
+	}
+}
+
+func TestLimiterWaitDone(t *testing.T) {
+	l := newTestLimiter(1, time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx); err != context.Canceled {
+		t.Errorf("Wait = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,124 @@
+package vkapi
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// Unsubscribe removes the handler it was returned for. Calling it more
+// than once has no effect.
+type Unsubscribe func()
+
+// HandlerPanicError is reported when an event handler panics.
+type HandlerPanicError struct {
+	Event string
+	Value interface{}
+	Stack []byte
+}
+
+func (e *HandlerPanicError) Error() string {
+	return fmt.Sprintf("%s handler panicked: %v", e.Event, e.Value)
+}
+
+// registry holds event handlers by event type. It is safe for
+// concurrent use and its zero value is empty.
+type registry[K comparable, E any] struct {
+	mu       sync.RWMutex
+	nextID   uint64
+	handlers map[K][]eventHandler[K, E]
+	catchAll []eventHandler[K, E]
+}
+
+type eventHandler[K comparable, E any] struct {
+	id uint64
+	f  func(ctx context.Context, key K, event E)
+}
+
+// add registers f for events of type key.
+func (r *registry[K, E]) add(key K, f func(ctx context.Context, key K, event E)) Unsubscribe {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.handlers == nil {
+		r.handlers = make(map[K][]eventHandler[K, E])
+	}
+	r.nextID++
+	id := r.nextID
+	r.handlers[key] = append(r.handlers[key], eventHandler[K, E]{id: id, f: f})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.handlers[key] = without(r.handlers[key], id)
+			if len(r.handlers[key]) == 0 {
+				delete(r.handlers, key)
+			}
+		})
+	}
+}
+
+// addAny registers f for events of every type.
+func (r *registry[K, E]) addAny(f func(ctx context.Context, key K, event E)) Unsubscribe {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	id := r.nextID
+	r.catchAll = append(r.catchAll, eventHandler[K, E]{id: id, f: f})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.catchAll = without(r.catchAll, id)
+		})
+	}
+}
+
+// without returns a copy of hs without the handler id, so that
+// slices being dispatched are not modified.
+func without[K comparable, E any](hs []eventHandler[K, E], id uint64) []eventHandler[K, E] {
+	r := make([]eventHandler[K, E], 0, len(hs))
+	for _, h := range hs {
+		if h.id != id {
+			r = append(r, h)
+		}
+	}
+	return r
+}
+
+// dispatch calls the handlers of key in order of registration,
+// then the catch-all ones. A panic in a handler is passed to report
+// and the rest of the handlers still run.
+func (r *registry[K, E]) dispatch(ctx context.Context, key K, event E, report func(ctx context.Context, err error)) {
+	r.mu.RLock()
+	hs, catchAll := r.handlers[key], r.catchAll
+	r.mu.RUnlock()
+
+	for _, h := range hs {
+		r.call(ctx, h, key, event, report)
+	}
+	for _, h := range catchAll {
+		r.call(ctx, h, key, event, report)
+	}
+}
+
+func (r *registry[K, E]) call(ctx context.Context, h eventHandler[K, E], key K, event E, report func(ctx context.Context, err error)) {
+	defer func() {
+		if v := recover(); v != nil {
+			report(ctx, &HandlerPanicError{Event: fmt.Sprint(key), Value: v, Stack: debug.Stack()})
+		}
+	}()
+	h.f(ctx, key, event)
+}
+
+// reportEventError logs err and passes it to EventErrorHandler.
+func (vk *VkAPI) reportEventError(ctx context.Context, err error) {
+	vk.logger().Error("event handler failed", "error", err)
+	if vk.EventErrorHandler != nil {
+		vk.EventErrorHandler(ctx, err)
+	}
+}
@@ -0,0 +1,121 @@
+package vkapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(r *registry[string, int], log *[]string) []Unsubscribe
+		// unsubscribe lists indexes of handlers removed before dispatch.
+		unsubscribe []int
+		want        string
+		wantPanics  int
+	}{
+		{
+			name: "order of registration",
+			setup: func(r *registry[string, int], log *[]string) []Unsubscribe {
+				return []Unsubscribe{
+					r.add("a", record(log, "a1")),
+					r.add("b", record(log, "b1")),
+					r.add("a", record(log, "a2")),
+				}
+			},
+			want: "a1 a2",
+		},
+		{
+			name: "catch-all after specific",
+			setup: func(r *registry[string, int], log *[]string) []Unsubscribe {
+				return []Unsubscribe{
+					r.addAny(record(log, "any")),
+					r.add("a", record(log, "a1")),
+				}
+			},
+			want: "a1 any",
+		},
+		{
+			name: "unsubscribe",
+			setup: func(r *registry[string, int], log *[]string) []Unsubscribe {
+				return []Unsubscribe{
+					r.add("a", record(log, "a1")),
+					r.add("a", record(log, "a2")),
+					r.addAny(record(log, "any")),
+				}
+			},
+			unsubscribe: []int{0, 2, 0},
+			want:        "a2",
+		},
+		{
+			name: "panic recovered",
+			setup: func(r *registry[string, int], log *[]string) []Unsubscribe {
+				return []Unsubscribe{
+					r.add("a", func(context.Context, string, int) { panic("boom") }),
+					r.add("a", record(log, "a2")),
+					r.addAny(func(context.Context, string, int) { panic("boom") }),
+				}
+			},
+			want:       "a2",
+			wantPanics: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				r      registry[string, int]
+				log    []string
+				panics []error
+			)
+			unsub := tt.setup(&r, &log)
+			for _, i := range tt.unsubscribe {
+				unsub[i]()
+			}
+
+			r.dispatch(context.Background(), "a", 1, func(_ context.Context, err error) {
+				panics = append(panics, err)
+			})
+
+			if got := strings.Join(log, " "); got != tt.want {
+				t.Errorf("called %q, want %q", got, tt.want)
+			}
+			if len(panics) != tt.wantPanics {
+				t.Fatalf("reported %v, want %d panics", panics, tt.wantPanics)
+			}
+			for _, err := range panics {
+				var pe *HandlerPanicError
+				if !errors.As(err, &pe) || pe.Event != "a" || pe.Value != "boom" || len(pe.Stack) == 0 {
+					t.Errorf("reported %#v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistryUnsubscribeDuringDispatch(t *testing.T) {
+	var (
+		r     registry[string, int]
+		log   []string
+		unsub Unsubscribe
+	)
+	r.add("a", func(context.Context, string, int) {
+		log = append(log, "a1")
+		unsub()
+	})
+	unsub = r.add("a", record(&log, "a2"))
+
+	report := func(_ context.Context, err error) { t.Error(err) }
+	r.dispatch(context.Background(), "a", 1, report)
+	r.dispatch(context.Background(), "a", 1, report)
+	if got := strings.Join(log, " "); got != "a1 a2 a1" {
+		t.Errorf("called %q, want %q", got, "a1 a2 a1")
+	}
+}
+
+func record(log *[]string, name string) func(context.Context, string, int) {
+	return func(context.Context, string, int) {
+		*log = append(*log, name)
+	}
+}
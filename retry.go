@@ -0,0 +1,129 @@
+package vkapi
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/url"
+	"time"
+)
+
+// RetryPolicy describes when and how failed requests are repeated.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	// BaseDelay is doubled after every attempt up to MaxDelay.
+	// The actual pause is picked at random between half and the full delay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Codes lists API error codes worth retrying.
+	Codes []ErrorCode
+	// RetryTransport enables retries of network failures and 5xx responses.
+	RetryTransport bool
+}
+
+// DefaultRetryPolicy retries rate limit, flood control and internal
+// server errors as well as transport failures.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    3,
+		BaseDelay:      300 * time.Millisecond,
+		MaxDelay:       5 * time.Second,
+		Codes:          []ErrorCode{ErrTooManyRequests, ErrFloodControl, ErrInternalServer},
+		RetryTransport: true,
+	}
+}
+
+// idempotencyKeys maps methods that are unsafe to repeat to the param
+// VK uses to drop duplicates. An empty param means never retry.
+var idempotencyKeys = map[string]string{
+	"messages.send":       "random_id",
+	"wall.post":           "guid",
+	"board.createComment": "guid",
+	"video.save":          "",
+}
+
+type transportError struct {
+	op  string
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.op + " error: " + e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+type requestFunc func(ctx context.Context, method string, params url.Values) (*APIResponse, error)
+
+func (p *RetryPolicy) do(ctx context.Context, method string, params url.Values, f requestFunc) (*APIResponse, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := f(ctx, method, params)
+		if err == nil || attempt >= p.MaxAttempts || !p.retryable(method, params, err) {
+			return resp, err
+		}
+
+		if err := sleep(ctx, p.backoff(attempt)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleep pauses for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// idempotent reports whether repeating the call cannot create duplicates.
+func idempotent(method string, params url.Values) bool {
+	key, ok := idempotencyKeys[method]
+	if !ok {
+		return true
+	}
+	return key != "" && params.Get(key) != "" && params.Get(key) != "0"
+}
+
+func (p *RetryPolicy) retryable(method string, params url.Values, err error) bool {
+	if !idempotent(method, params) {
+		return false
+	}
+
+	var te *transportError
+	if errors.As(err, &te) {
+		return p.RetryTransport
+	}
+
+	for _, c := range p.Codes {
+		if errors.Is(err, c) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	return backoff(p.BaseDelay, p.MaxDelay, attempt)
+}
+
+// backoff doubles base for every attempt up to max and picks a random
+// delay from the upper half of it.
+func backoff(base, max time.Duration, attempt int) time.Duration {
+	d := base << uint(attempt-1)
+	if d <= 0 || (max > 0 && d > max) {
+		d = max
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
@@ -0,0 +1,122 @@
+package vkapi
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	p := DefaultRetryPolicy()
+	tooMany := APIError{Code: int(ErrTooManyRequests)}
+	netErr := &transportError{op: "request", err: errors.New("connection reset")}
+
+	tests := []struct {
+		name   string
+		method string
+		params url.Values
+		err    error
+		want   bool
+	}{
+		{"listed code", "users.get", nil, tooMany, true},
+		{"other code", "users.get", nil, APIError{Code: int(ErrAccessDenied)}, false},
+		{"transport", "users.get", nil, netErr, true},
+		{"plain error", "users.get", nil, errors.New("decode"), false},
+		{"send without random_id", "messages.send", nil, tooMany, false},
+		{"send with zero random_id", "messages.send", url.Values{"random_id": {"0"}}, tooMany, false},
+		{"send with random_id", "messages.send", url.Values{"random_id": {"42"}}, netErr, true},
+		{"post with guid", "wall.post", url.Values{"guid": {"g"}}, tooMany, true},
+		{"never retried", "video.save", url.Values{"guid": {"g"}}, netErr, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.retryable(tt.method, tt.params, tt.err); got != tt.want {
+				t.Errorf("retryable = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	p.RetryTransport = false
+	if p.retryable("users.get", nil, netErr) {
+		t.Error("transport error retried with RetryTransport off")
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	tooMany := APIError{Code: int(ErrTooManyRequests)}
+
+	tests := []struct {
+		name     string
+		errs     []error
+		attempts int
+		wantErr  error
+	}{
+		{"success", []error{nil}, 1, nil},
+		{"retried until success", []error{tooMany, tooMany, nil}, 3, nil},
+		{"gives up after MaxAttempts", []error{tooMany, tooMany, tooMany, nil}, 3, ErrTooManyRequests},
+		{"not retryable", []error{APIError{Code: int(ErrAccessDenied)}, nil}, 1, ErrAccessDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &RetryPolicy{
+				MaxAttempts: 3,
+				BaseDelay:   time.Millisecond,
+				Codes:       []ErrorCode{ErrTooManyRequests},
+			}
+			attempts := 0
+			f := func(context.Context, string, url.Values) (*APIResponse, error) {
+				err := tt.errs[attempts]
+				attempts++
+				if err != nil {
+					return nil, err
+				}
+				return &APIResponse{}, nil
+			}
+
+			_, err := p.do(context.Background(), "users.get", url.Values{}, f)
+			if attempts != tt.attempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.attempts)
+			}
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDoCancelled(t *testing.T) {
+	p := &RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour, Codes: []ErrorCode{ErrTooManyRequests}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := p.do(ctx, "users.get", url.Values{}, func(context.Context, string, url.Values) (*APIResponse, error) {
+		return nil, APIError{Code: int(ErrTooManyRequests)}
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		base, max time.Duration
+		attempt   int
+		min, upTo time.Duration
+	}{
+		{100 * time.Millisecond, time.Second, 1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{100 * time.Millisecond, time.Second, 3, 200 * time.Millisecond, 400 * time.Millisecond},
+		{100 * time.Millisecond, time.Second, 10, 500 * time.Millisecond, time.Second},
+		{100 * time.Millisecond, 0, 2, 100 * time.Millisecond, 200 * time.Millisecond},
+		{0, 0, 1, 0, 0},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if d := backoff(tt.base, tt.max, tt.attempt); d < tt.min || d > tt.upTo {
+				t.Errorf("backoff(%v, %v, %d) = %v, want between %v and %v",
+					tt.base, tt.max, tt.attempt, d, tt.min, tt.upTo)
+			}
+		}
+	}
+}
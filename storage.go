@@ -1,73 +1,100 @@
 package vkapi
 
 import (
+	"context"
 	"encoding/json"
 	"net/url"
-	"strconv"
-	"strings"
 )
 
 type StorageSetReq struct {
-	Key    string
-	Value  string
-	UserID int
+	Key    string `vk:"key"`
+	Value  string `vk:"value"`
+	UserID int    `vk:"user_id,omitempty"`
 }
 
 func (StorageSetReq) Name() string {
 	return "storage.set"
 }
 
+// EncodeValues returns the params of s.
+func (s *StorageSetReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(s)
+}
+
+// Values returns the params of s.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
 func (s *StorageSetReq) Values() url.Values {
-	v := url.Values{}
-	v.Set("key", s.Key)
-	v.Set("value", s.Value)
-	if s.UserID != 0 {
-		v.Set("user_id", strconv.Itoa(s.UserID))
-	}
-	return v
+	values, _ := EncodeValues(s)
+	return values
+}
+
+func (s StorageSetReq) Validate() error {
+	return validate(s.Name(),
+		required("key", s.Key != ""),
+		maxLength("key", s.Key, 100),
+		rule{ok: len(s.Value) <= 4096, param: "value", reason: "must be at most 4096 bytes long"},
+	)
 }
 
 type StorageGetKeysReq struct {
-	UserID int
-	Offset int
-	Count  int
+	UserID int `vk:"user_id,omitempty"`
+	Offset int `vk:"offset,omitempty"`
+	Count  int `vk:"count,omitempty"`
 }
 
 func (StorageGetKeysReq) Name() string {
 	return "storage.getKeys"
 }
 
+// EncodeValues returns the params of s.
+func (s *StorageGetKeysReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(s)
+}
+
+// Values returns the params of s.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
 func (s *StorageGetKeysReq) Values() url.Values {
-	v := url.Values{}
-	v.Set("user_id", strconv.Itoa(s.UserID))
-	v.Set("offset", strconv.Itoa(s.Offset))
-	if s.Count != 0 {
-		v.Set("count", strconv.Itoa(s.Count))
-	}
-	return v
+	values, _ := EncodeValues(s)
+	return values
+}
+
+func (s StorageGetKeysReq) Validate() error {
+	return validate(s.Name(),
+		inRange("count", s.Count, 0, 1000),
+		rule{ok: s.Offset >= 0, param: "offset", reason: "must not be negative"},
+	)
 }
 
 type StorageGetReq struct {
-	Key    string
-	Keys   []string
-	UserID int
+	Key    string   `vk:"key,omitempty"`
+	Keys   []string `vk:"keys,omitempty"`
+	UserID int      `vk:"user_id,omitempty"`
 }
 
 func (StorageGetReq) Name() string {
 	return "storage.get"
 }
 
+// EncodeValues returns the params of s.
+func (s StorageGetReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(s)
+}
+
+// Values returns the params of s.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
 func (s StorageGetReq) Values() url.Values {
-	v := url.Values{}
-	if len(s.Key) > 0 {
-		v.Set("key", s.Key)
-	}
+	values, _ := EncodeValues(s)
+	return values
+}
 
-	if len(s.Keys) > 0 {
-		v.Set("keys", strings.Join(s.Keys, ","))
-	}
-	v.Set("user_id", strconv.Itoa(s.UserID))
-	return v
+func (s StorageGetReq) Validate() error {
+	return validate(s.Name(),
+		requiredOneOf("key, keys", s.Key != "" || len(s.Keys) > 0),
+		maxItems("keys", len(s.Keys), 1000),
+	)
 }
 
 type StorageGetResp struct {
@@ -79,60 +106,51 @@ type StorageGetResp struct {
 //
 // See https://vk.com/dev/storage.set
 func (vk *VkAPI) StorageSet(v *StorageSetReq) (bool, error) {
-	resp, err := vk.MakeRequest(v.Name(), v.Values())
-	if err != nil {
-		return false, err
-	}
+	return vk.StorageSetContext(context.Background(), v)
+}
 
-	var r float64
-	if err := json.Unmarshal(resp.Response, &r); err != nil {
+// StorageSetContext is like StorageSet but uses ctx for the request.
+func (vk *VkAPI) StorageSetContext(ctx context.Context, v *StorageSetReq) (bool, error) {
+	r, err := Call[int](ctx, vk, v)
+	if err != nil {
 		return false, err
 	}
-
-	if r == 0 {
-		return false, nil
-	}
-
-	return true, nil
+	return r != 0, nil
 }
 
 // StorageGetKeysReq returns the names of all variables.
 //
 // See https://vk.com/dev/storage.get
 func (vk *VkAPI) StorageGetKeys(v *StorageGetKeysReq) ([]string, error) {
-	resp, err := vk.MakeRequest(v.Name(), v.Values())
-	if err != nil {
-		return nil, err
-	}
-
-	var r []string
-	if err := json.Unmarshal(resp.Response, &r); err != nil {
-		return nil, err
-	}
+	return vk.StorageGetKeysContext(context.Background(), v)
+}
 
-	return r, nil
+// StorageGetKeysContext is like StorageGetKeys but uses ctx for the request.
+func (vk *VkAPI) StorageGetKeysContext(ctx context.Context, v *StorageGetKeysReq) ([]string, error) {
+	return Call[[]string](ctx, vk, v)
 }
 
 // StorageGet returns a value of variable with the name set by key parameter.
 //
 // See https://vk.com/dev/storage.get
 func (vk *VkAPI) StorageGet(v *StorageGetReq) ([]StorageGetResp, error) {
-	resp, err := vk.MakeRequest(v.Name(), v.Values())
-	if err != nil {
-		return nil, err
-	}
+	return vk.StorageGetContext(context.Background(), v)
+}
 
-	var r interface{}
-	if err := json.Unmarshal(resp.Response, &r); err != nil {
+// StorageGetContext is like StorageGet but uses ctx for the request.
+func (vk *VkAPI) StorageGetContext(ctx context.Context, v *StorageGetReq) ([]StorageGetResp, error) {
+	resp, err := Call[json.RawMessage](ctx, vk, v)
+	if err != nil {
 		return nil, err
 	}
 
-	if _, ok := r.(string); ok {
-		return []StorageGetResp{{Key: v.Key, Value: r.(string)}}, nil
+	var r string
+	if err := json.Unmarshal(resp, &r); err == nil {
+		return []StorageGetResp{{Key: v.Key, Value: r}}, nil
 	}
 
 	var m []StorageGetResp
-	if err := json.Unmarshal(resp.Response, &m); err != nil {
+	if err := json.Unmarshal(resp, &m); err != nil {
 		return nil, err
 	}
 	return m, nil
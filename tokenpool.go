@@ -0,0 +1,156 @@
+package vkapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNoTokens is returned when every token of a pool is out of rotation.
+var ErrNoTokens = errors.New("no healthy tokens in pool")
+
+// TokenPool spreads requests over several access tokens in round-robin
+// order. Tokens rejected with ErrAuthFailed are removed from rotation
+// for good; tokens hitting rate limits repeatedly are paused for Cooldown.
+// Assign a pool to VkAPI.TokenSource to use it.
+type TokenPool struct {
+	// MaxRateLimited is the number of consecutive rate limit errors
+	// that pauses a token.
+	MaxRateLimited int
+	// Cooldown is how long a paused token stays out of rotation.
+	Cooldown time.Duration
+
+	mu     sync.Mutex
+	tokens []*pooledToken
+	next   int
+}
+
+// TokenStats describes the usage of a pool token.
+type TokenStats struct {
+	// Token is the access token with all but the last 4 characters masked.
+	Token       string
+	Requests    int64
+	Errors      int64
+	AuthErrors  int64
+	RateLimited int64
+	Revoked     bool
+	PausedUntil time.Time
+}
+
+type pooledToken struct {
+	token   string
+	limiter RateLimiter
+	stats   TokenStats
+	// streak counts consecutive rate limit errors.
+	streak int
+}
+
+// NewTokenPool returns a pool of tokens each limited to rps requests
+// per second. rps <= 0 disables per-token limits. VkAPI.Limiter, if
+// set, still applies on top of them.
+func NewTokenPool(rps int, tokens ...string) *TokenPool {
+	p := &TokenPool{
+		MaxRateLimited: 3,
+		Cooldown:       time.Minute,
+	}
+	for _, t := range tokens {
+		pt := &pooledToken{token: t}
+		pt.stats.Token = maskToken(t)
+		if rps > 0 {
+			pt.limiter = NewLimiter(rps)
+		}
+		p.tokens = append(p.tokens, pt)
+	}
+	return p
+}
+
+// Stats returns a snapshot of per-token statistics.
+func (p *TokenPool) Stats() []TokenStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]TokenStats, len(p.tokens))
+	for i, t := range p.tokens {
+		stats[i] = t.stats
+	}
+	return stats
+}
+
+// Token picks the next token in rotation and waits for its limiter.
+func (p *TokenPool) Token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	var picked *pooledToken
+	now := time.Now()
+	for i := 0; i < len(p.tokens); i++ {
+		t := p.tokens[(p.next+i)%len(p.tokens)]
+		if t.stats.Revoked || now.Before(t.stats.PausedUntil) {
+			continue
+		}
+		picked = t
+		p.next = (p.next + i + 1) % len(p.tokens)
+		break
+	}
+	if picked != nil {
+		picked.stats.Requests++
+	}
+	p.mu.Unlock()
+
+	if picked == nil {
+		return "", ErrNoTokens
+	}
+	if picked.limiter != nil {
+		if err := picked.limiter.Wait(ctx); err != nil {
+			return "", err
+		}
+	}
+	return picked.token, nil
+}
+
+// ReportToken updates the health of token after a request made with it.
+func (p *TokenPool) ReportToken(token string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var t *pooledToken
+	for _, pt := range p.tokens {
+		if pt.token == token {
+			t = pt
+			break
+		}
+	}
+	if t == nil {
+		return
+	}
+
+	if err == nil {
+		t.streak = 0
+		return
+	}
+	t.stats.Errors++
+
+	switch {
+	case errors.Is(err, ErrAuthFailed):
+		t.stats.AuthErrors++
+		t.stats.Revoked = true
+	case errors.Is(err, ErrTooManyRequests) || errors.Is(err, ErrRateLimit):
+		t.stats.RateLimited++
+		t.streak++
+		if p.MaxRateLimited > 0 && t.streak >= p.MaxRateLimited {
+			t.stats.PausedUntil = time.Now().Add(p.Cooldown)
+			t.streak = 0
+		}
+	}
+}
+
+func maskToken(token string) string {
+	if len(token) <= 4 {
+		return "****"
+	}
+	b := make([]byte, len(token))
+	for i := range b {
+		b[i] = '*'
+	}
+	copy(b[len(b)-4:], token[len(token)-4:])
+	return string(b)
+}
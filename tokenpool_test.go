@@ -0,0 +1,82 @@
+package vkapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenPoolRotation(t *testing.T) {
+	authFailed := APIError{Code: int(ErrAuthFailed)}
+	tooMany := APIError{Code: int(ErrTooManyRequests)}
+
+	tests := []struct {
+		name    string
+		reports map[string][]error
+		want    []string
+		wantErr error
+	}{
+		{"round robin", nil, []string{"aaaa", "bbbb", "cccc", "aaaa"}, nil},
+		{"revoked", map[string][]error{"bbbb": {authFailed}}, []string{"aaaa", "cccc", "aaaa"}, nil},
+		{"paused after streak", map[string][]error{"aaaa": {tooMany, tooMany}}, []string{"bbbb", "cccc", "bbbb"}, nil},
+		{"streak reset by success", map[string][]error{"aaaa": {tooMany, nil, tooMany}}, []string{"aaaa", "bbbb", "cccc"}, nil},
+		{"other errors ignored", map[string][]error{"aaaa": {errors.New("timeout"), errors.New("timeout")}}, []string{"aaaa", "bbbb"}, nil},
+		{"all revoked", map[string][]error{"aaaa": {authFailed}, "bbbb": {authFailed}, "cccc": {authFailed}}, nil, ErrNoTokens},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewTokenPool(0, "aaaa", "bbbb", "cccc")
+			p.MaxRateLimited = 2
+			for token, errs := range tt.reports {
+				for _, err := range errs {
+					p.ReportToken(token, err)
+				}
+			}
+
+			for i, want := range tt.want {
+				got, err := p.Token(context.Background())
+				if err != nil || got != want {
+					t.Fatalf("Token #%d = %q, %v, want %q", i, got, err, want)
+				}
+			}
+			if tt.wantErr != nil {
+				if _, err := p.Token(context.Background()); err != tt.wantErr {
+					t.Errorf("Token err = %v, want %v", err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenPoolCooldown(t *testing.T) {
+	p := NewTokenPool(0, "aaaa")
+	p.MaxRateLimited = 1
+	p.Cooldown = 20 * time.Millisecond
+	p.ReportToken("aaaa", APIError{Code: int(ErrRateLimit)})
+
+	if _, err := p.Token(context.Background()); err != ErrNoTokens {
+		t.Fatalf("Token err = %v, want %v", err, ErrNoTokens)
+	}
+	time.Sleep(p.Cooldown)
+	if got, err := p.Token(context.Background()); err != nil || got != "aaaa" {
+		t.Errorf("Token after cooldown = %q, %v", got, err)
+	}
+}
+
+func TestTokenPoolStats(t *testing.T) {
+	p := NewTokenPool(0, "secret-token", "xy")
+	p.Token(context.Background())
+	p.ReportToken("secret-token", APIError{Code: int(ErrAuthFailed)})
+
+	stats := p.Stats()
+	want := []TokenStats{
+		{Token: "********oken", Requests: 1, Errors: 1, AuthErrors: 1, Revoked: true},
+		{Token: "****"},
+	}
+	for i := range want {
+		if stats[i] != want[i] {
+			t.Errorf("stats[%d] = %+v, want %+v", i, stats[i], want[i])
+		}
+	}
+}
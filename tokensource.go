@@ -0,0 +1,122 @@
+package vkapi
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenSource supplies the access token for every request.
+// Implementations must be safe for concurrent use.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenReporter is implemented by token sources that want to know
+// the outcome of requests made with their tokens.
+type TokenReporter interface {
+	ReportToken(token string, err error)
+}
+
+// TokenSourceFunc adapts a callback to TokenSource. It is called
+// before every request.
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+type staticTokenSource string
+
+// StaticTokenSource returns a TokenSource that always returns token.
+func StaticTokenSource(token string) TokenSource {
+	return staticTokenSource(token)
+}
+
+func (s staticTokenSource) Token(context.Context) (string, error) {
+	return string(s), nil
+}
+
+// FileTokenSource reads the token from a file and reloads it when the
+// file modification time changes. The file is checked at most once
+// per Interval. If a reload fails, e.g. while the file is being
+// rewritten, the last token keeps being served.
+type FileTokenSource struct {
+	Path     string
+	Interval time.Duration
+	// Logger, if set, receives failed reloads.
+	Logger Logger
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+	checked time.Time
+}
+
+// NewFileTokenSource returns a source reading the token from path.
+func NewFileTokenSource(path string) *FileTokenSource {
+	return &FileTokenSource{
+		Path:     path,
+		Interval: 5 * time.Second,
+	}
+}
+
+func (s *FileTokenSource) Token(context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.token != "" && now.Sub(s.checked) < s.Interval {
+		return s.token, nil
+	}
+	s.checked = now
+
+	fi, err := os.Stat(s.Path)
+	if err != nil {
+		return s.cached(err)
+	}
+	if s.token != "" && fi.ModTime().Equal(s.modTime) {
+		return s.token, nil
+	}
+
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return s.cached(err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return s.cached(errors.New("token file " + s.Path + " is empty"))
+	}
+	s.token = token
+	s.modTime = fi.ModTime()
+	return s.token, nil
+}
+
+// cached returns the last token if there is one and err otherwise.
+// It must be called with s.mu held.
+func (s *FileTokenSource) cached(err error) (string, error) {
+	if s.token == "" {
+		return "", err
+	}
+	if s.Logger != nil {
+		s.Logger.Warn("token file reload failed", "path", s.Path, "error", err)
+	}
+	return s.token, nil
+}
+
+// token returns the token for the next request.
+func (vk *VkAPI) token(ctx context.Context) (string, error) {
+	if vk.TokenSource == nil {
+		return vk.Token, nil
+	}
+	return vk.TokenSource.Token(ctx)
+}
+
+func (vk *VkAPI) reportToken(token string, err error) {
+	if r, ok := vk.TokenSource.(TokenReporter); ok {
+		r.ReportToken(token, err)
+	}
+}
@@ -0,0 +1,132 @@
+package vkapi_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+	"github.com/seilem/vk-golang-sdk/vktest"
+)
+
+func TestFileTokenSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		rotate  func(t *testing.T, path string)
+		want    string
+		wantLog bool
+	}{
+		{
+			name:   "unchanged",
+			rotate: func(*testing.T, string) {},
+			want:   "first",
+		},
+		{
+			name: "rotated",
+			rotate: func(t *testing.T, path string) {
+				writeToken(t, path, "second\n", time.Now().Add(time.Minute))
+			},
+			want: "second",
+		},
+		{
+			name: "removed",
+			rotate: func(t *testing.T, path string) {
+				os.Remove(path)
+			},
+			want:    "first",
+			wantLog: true,
+		},
+		{
+			name: "truncated",
+			rotate: func(t *testing.T, path string) {
+				writeToken(t, path, "", time.Now().Add(time.Minute))
+			},
+			want:    "first",
+			wantLog: true,
+		},
+		{
+			name: "unreadable",
+			rotate: func(t *testing.T, path string) {
+				os.Remove(path)
+				if err := os.Mkdir(path, 0o700); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want:    "first",
+			wantLog: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "token")
+			writeToken(t, path, "first", time.Now())
+
+			var buf bytes.Buffer
+			s := vkapi.NewFileTokenSource(path)
+			s.Interval = 0
+			s.Logger = slog.New(slog.NewTextHandler(&buf, nil))
+			if got, err := s.Token(context.Background()); err != nil || got != "first" {
+				t.Fatalf("Token = %q, %v, want %q", got, err, "first")
+			}
+
+			tt.rotate(t, path)
+			got, err := s.Token(context.Background())
+			if err != nil || got != tt.want {
+				t.Errorf("Token = %q, %v, want %q", got, err, tt.want)
+			}
+			if logged := strings.Contains(buf.String(), "token file reload failed"); logged != tt.wantLog {
+				t.Errorf("logged %q, want log %v", buf.String(), tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestFileTokenSourceMissing(t *testing.T) {
+	s := vkapi.NewFileTokenSource(filepath.Join(t.TempDir(), "token"))
+	if _, err := s.Token(context.Background()); err == nil {
+		t.Error("Token of a missing file returned no error")
+	}
+}
+
+func writeToken(t *testing.T, path, token string, mtime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(token), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTokenSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		source vkapi.TokenSource
+		want   string
+	}{
+		{"none", nil, "token"},
+		{"static", vkapi.StaticTokenSource("static"), "static"},
+		{"func", vkapi.TokenSourceFunc(func(context.Context) (string, error) { return "func", nil }), "func"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := vktest.NewServer()
+			defer srv.Close()
+			srv.Handle("users.get", []vkapi.User{})
+
+			vk := srv.Client("token")
+			vk.TokenSource = tt.source
+			if _, err := vk.UsersGetContext(context.Background(), &vkapi.UsersGetReq{}); err != nil {
+				t.Fatal(err)
+			}
+			if got := srv.Requests("users.get")[0].Get("access_token"); got != tt.want {
+				t.Errorf("access_token = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
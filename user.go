@@ -1,41 +1,44 @@
 package vkapi
 
 import (
-	"encoding/json"
+	"context"
 	"net/url"
-	"strings"
 )
 
 type UsersGetReq struct {
-	UserIDs  []string
-	Fields   []string
-	NameCase string
+	UserIDs  []string `vk:"user_ids,omitempty"`
+	Fields   []string `vk:"fields,omitempty"`
+	NameCase string   `vk:"name_case,omitempty"`
 }
 
 func (UsersGetReq) Name() string {
 	return "users.get"
 }
 
+// EncodeValues returns the params of u.
+func (u *UsersGetReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(u)
+}
+
+// Values returns the params of u.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
 func (u *UsersGetReq) Values() url.Values {
-	v := url.Values{}
-	v.Set("user_ids", strings.Join(u.UserIDs, ","))
-	v.Set("fields", strings.Join(u.Fields, ","))
-	if len(u.NameCase) > 0 {
-		v.Set("name_case", u.NameCase)
-	}
-	return v
+	values, _ := EncodeValues(u)
+	return values
 }
 
-func (vk *VkAPI) UsersGet(v *UsersGetReq) ([]User, error) {
-	resp, err := vk.MakeRequest(v.Name(), v.Values())
-	if err != nil {
-		return nil, err
-	}
+func (u UsersGetReq) Validate() error {
+	return validate(u.Name(),
+		maxItems("user_ids", len(u.UserIDs), 1000),
+	)
+}
 
-	var u []User
-	if err := json.Unmarshal(resp.Response, &u); err != nil {
-		return nil, err
-	}
+func (vk *VkAPI) UsersGet(v *UsersGetReq) ([]User, error) {
+	return vk.UsersGetContext(context.Background(), v)
+}
 
-	return u, nil
+// UsersGetContext is like UsersGet but uses ctx for the request.
+func (vk *VkAPI) UsersGetContext(ctx context.Context, v *UsersGetReq) ([]User, error) {
+	return Call[[]User](ctx, vk, v)
 }
@@ -0,0 +1,91 @@
+package vkapi
+
+import (
+	"fmt"
+	"net/url"
+	"unicode/utf8"
+)
+
+const (
+	// MaxMessageLength is the maximum length of a message text in characters.
+	MaxMessageLength = 4096
+	// MaxAttachments is the maximum number of attachments of a message,
+	// post or comment.
+	MaxAttachments = 10
+)
+
+// Validator is implemented by requests that check their params before
+// being sent. Call returns its error without making a request.
+type Validator interface {
+	Validate() error
+}
+
+// ParamError reports a request param rejected by Validate.
+type ParamError struct {
+	Method string
+	Param  string
+	Reason string
+}
+
+func (e *ParamError) Error() string {
+	return e.Method + ": " + e.Param + " " + e.Reason
+}
+
+// prepare validates m and encodes its params.
+func prepare(m Method) (url.Values, error) {
+	if v, ok := m.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return methodValues(m)
+}
+
+type rule struct {
+	ok     bool
+	param  string
+	reason string
+}
+
+// validate returns a ParamError for the first broken rule.
+func validate(method string, rules ...rule) error {
+	for _, r := range rules {
+		if !r.ok {
+			return &ParamError{Method: method, Param: r.param, Reason: r.reason}
+		}
+	}
+	return nil
+}
+
+func required(param string, ok bool) rule {
+	return rule{ok: ok, param: param, reason: "is required"}
+}
+
+// requiredOneOf takes a comma separated list of params.
+func requiredOneOf(params string, ok bool) rule {
+	return rule{ok: ok, param: "one of " + params, reason: "is required"}
+}
+
+func maxLength(param, s string, n int) rule {
+	return rule{
+		ok:     utf8.RuneCountInString(s) <= n,
+		param:  param,
+		reason: fmt.Sprintf("must be at most %d characters long", n),
+	}
+}
+
+func maxItems(param string, count, n int) rule {
+	return rule{
+		ok:     count <= n,
+		param:  param,
+		reason: fmt.Sprintf("must have at most %d items", n),
+	}
+}
+
+func inRange(param string, v, min, max int) rule {
+	return rule{
+		ok:     v >= min && v <= max,
+		param:  param,
+		reason: fmt.Sprintf("must be between %d and %d", min, max),
+	}
+}
@@ -0,0 +1,59 @@
+package vkapi_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+	"github.com/seilem/vk-golang-sdk/vktest"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       vkapi.Validator
+		wantParam string
+	}{
+		{"valid message", vkapi.MsgReq{PeerID: 1, Message: "hi"}, ""},
+		{"no recipient", vkapi.MsgReq{Message: "hi"}, "one of peer_id, user_id, domain, chat_id, user_ids"},
+		{"no content", vkapi.MsgReq{PeerID: 1}, "one of message, attachment, sticker_id, forward_messages, forward"},
+		{"long message", vkapi.MsgReq{PeerID: 1, Message: strings.Repeat("я", vkapi.MaxMessageLength+1)}, "message"},
+		{"max length in characters", vkapi.MsgReq{PeerID: 1, Message: strings.Repeat("я", vkapi.MaxMessageLength)}, ""},
+		{"too many attachments", vkapi.MsgReq{PeerID: 1, Attachments: make([]string, vkapi.MaxAttachments+1)}, "attachment"},
+		{"unknown activity", vkapi.MsgSetActivityReq{PeerID: 1, Type: "walking"}, "type"},
+		{"no message ids", vkapi.MsgDeleteReq{}, "message_ids"},
+		{"storage key", vkapi.StorageSetReq{}, "key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if tt.wantParam == "" {
+				if err != nil {
+					t.Errorf("Validate = %v, want nil", err)
+				}
+				return
+			}
+			var pe *vkapi.ParamError
+			if !errors.As(err, &pe) || pe.Param != tt.wantParam {
+				t.Errorf("Validate = %v, want error for %s", err, tt.wantParam)
+			}
+		})
+	}
+}
+
+func TestValidateBeforeSend(t *testing.T) {
+	srv := vktest.NewServer()
+	defer srv.Close()
+	srv.Handle("messages.send", 1)
+
+	_, err := srv.Client("token").MsgSendContext(context.Background(), &vkapi.MsgReq{Message: "hi"})
+	var pe *vkapi.ParamError
+	if !errors.As(err, &pe) || pe.Method != "messages.send" {
+		t.Errorf("err = %v, want a ParamError", err)
+	}
+	if n := len(srv.Requests("")); n != 0 {
+		t.Errorf("%d requests sent for an invalid request", n)
+	}
+}
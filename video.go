@@ -2,23 +2,22 @@ package vkapi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
 	"net/url"
-	"strconv"
-	"strings"
 )
 
 type VideoGetReq struct {
-	OwnerID  int
-	Videos   []string
-	AlbumID  int
-	Offset   int
-	Count    int
-	Extended bool
+	OwnerID  int      `vk:"owner_id,omitempty"`
+	Videos   []string `vk:"videos,omitempty"`
+	AlbumID  int      `vk:"album_id,omitempty"`
+	Offset   int      `vk:"offset,omitempty"`
+	Count    int      `vk:"count,omitempty"`
+	Extended bool     `vk:"extended,omitempty"`
 }
 
 type UploadedVideoResp struct {
@@ -30,58 +29,49 @@ func (VideoGetReq) Name() string {
 	return "video.get"
 }
 
-func (r *VideoGetReq) Values() url.Values {
-	v := url.Values{}
-	if r.OwnerID != 0 {
-		v.Set("owner_id", strconv.Itoa(r.OwnerID))
-	}
-
-	v.Set("videos", strings.Join(r.Videos, ","))
-
-	if r.AlbumID != 0 {
-		v.Set("album_id", strconv.Itoa(r.AlbumID))
-	}
-
-	v.Set("offset", strconv.Itoa(r.Offset))
+// EncodeValues returns the params of r.
+func (r *VideoGetReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(r)
+}
 
-	if r.Count != 0 {
-		v.Set("count", strconv.Itoa(r.Count))
-	}
-	v.Set("extended", strconv.Itoa(btoi(r.Extended)))
+// Values returns the params of r.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
+func (r *VideoGetReq) Values() url.Values {
+	values, _ := EncodeValues(r)
+	return values
+}
 
-	return v
+func (r VideoGetReq) Validate() error {
+	return validate(r.Name(),
+		inRange("count", r.Count, 0, 200),
+		rule{ok: r.Offset >= 0, param: "offset", reason: "must not be negative"},
+		maxItems("videos", len(r.Videos), 200),
+	)
 }
 
 type VideoSaveReq struct {
-	VideoName   string
-	Description string
-	Link        string
-	GroupID     int64 `json:"group_id"`
+	VideoName   string `vk:"name,omitempty"`
+	Description string `vk:"description,omitempty"`
+	Link        string `vk:"link,omitempty"`
+	GroupID     int64  `vk:"group_id,omitempty" json:"group_id"`
 }
 
 func (VideoSaveReq) Name() string {
 	return "video.save"
 }
 
-func (r *VideoSaveReq) Values() url.Values {
-	v := url.Values{}
-	if len(r.Link) != 0 {
-		v.Set("link", r.Link)
-	}
-
-	if len(r.VideoName) > 0 {
-		v.Set("name", r.VideoName)
-	}
-
-	if len(r.Description) > 0 {
-		v.Set("description", r.Description)
-	}
-
-	if r.GroupID > 0 {
-		v.Set("group_id", strconv.FormatInt(r.GroupID, 10))
-	}
+// EncodeValues returns the params of r.
+func (r *VideoSaveReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(r)
+}
 
-	return v
+// Values returns the params of r.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
+func (r *VideoSaveReq) Values() url.Values {
+	values, _ := EncodeValues(r)
+	return values
 }
 
 type VideoSaveResp struct {
@@ -93,35 +83,30 @@ type VideoSaveResp struct {
 	VideoID     int    `json:"video_id"`
 }
 
-func (vk *VkAPI) VideoGet(r *UsersGetReq) (*Videos, error) {
-	resp, err := vk.MakeRequest(r.Name(), r.Values())
-	if err != nil {
-		return nil, err
-	}
-
-	var v Videos
-	if err := json.Unmarshal(resp.Response, &v); err != nil {
-		return nil, err
-	}
+func (vk *VkAPI) VideoGet(r *VideoGetReq) (*Videos, error) {
+	return vk.VideoGetContext(context.Background(), r)
+}
 
-	return &v, nil
+// VideoGetContext is like VideoGet but uses ctx for the request.
+func (vk *VkAPI) VideoGetContext(ctx context.Context, r *VideoGetReq) (*Videos, error) {
+	return Call[*Videos](ctx, vk, r)
 }
 
 func (vk *VkAPI) VideoSave(r *VideoSaveReq) (*VideoSaveResp, error) {
-	resp, err := vk.MakeRequest(r.Name(), r.Values())
-	if err != nil {
-		return nil, err
-	}
-
-	var v VideoSaveResp
-	if err := json.Unmarshal(resp.Response, &v); err != nil {
-		return nil, err
-	}
+	return vk.VideoSaveContext(context.Background(), r)
+}
 
-	return &v, nil
+// VideoSaveContext is like VideoSave but uses ctx for the request.
+func (vk *VkAPI) VideoSaveContext(ctx context.Context, r *VideoSaveReq) (*VideoSaveResp, error) {
+	return Call[*VideoSaveResp](ctx, vk, r)
 }
 
 func MakeUploadVideoRequest(uploadURL string, file File) (*http.Request, error) {
+	return MakeUploadVideoRequestContext(context.Background(), uploadURL, file)
+}
+
+// MakeUploadVideoRequestContext is like MakeUploadVideoRequest but binds ctx to the request.
+func MakeUploadVideoRequestContext(ctx context.Context, uploadURL string, file File) (*http.Request, error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
@@ -137,7 +122,7 @@ func MakeUploadVideoRequest(uploadURL string, file File) (*http.Request, error)
 		return nil, err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, uploadURL, body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, body)
 	if err != nil {
 		return nil, err
 	}
@@ -146,15 +131,24 @@ func MakeUploadVideoRequest(uploadURL string, file File) (*http.Request, error)
 }
 
 func (vk *VkAPI) UploadVideo(uploadURL string, file File) (*UploadedVideoResp, error) {
-	req, err := MakeUploadVideoRequest(uploadURL, file)
+	return vk.UploadVideoContext(context.Background(), uploadURL, file)
+}
+
+// UploadVideoContext is like UploadVideo but uses ctx for the request.
+func (vk *VkAPI) UploadVideoContext(ctx context.Context, uploadURL string, file File) (*UploadedVideoResp, error) {
+	req, err := MakeUploadVideoRequestContext(ctx, uploadURL, file)
 	if err != nil {
 		return nil, err
 	}
 
 	resp, err := vk.Client.Do(req)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, err
 	}
+	defer resp.Body.Close()
 
 	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
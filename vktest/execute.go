@@ -0,0 +1,89 @@
+package vktest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+)
+
+var executeCall = regexp.MustCompile(`^API\.([a-zA-Z]+\.[a-zA-Z]+)\(`)
+
+// serveExecute runs code of the form sent by vkapi.Batcher,
+// return [API.method({...}),...]; against the registered handlers.
+// Failed calls return false and an entry of execute_errors.
+func (s *Server) serveExecute(w http.ResponseWriter, params url.Values) {
+	calls, err := parseExecute(params.Get("code"))
+	if err != nil {
+		writeJSON(w, map[string]interface{}{
+			"error": apiError(vkapi.APIError{Code: int(vkapi.ErrInvalidParam), Msg: err.Error()}, params),
+		})
+		return
+	}
+
+	var (
+		results = make([]interface{}, 0, len(calls))
+		errs    []vkapi.APIError
+	)
+	for _, c := range calls {
+		resp, err := s.call(c.method, c.params)
+		if err != nil {
+			e := apiError(err, c.params)
+			e.Method = c.method
+			errs = append(errs, e)
+			results = append(results, false)
+			continue
+		}
+		results = append(results, resp)
+	}
+
+	body := map[string]interface{}{"response": results}
+	if len(errs) > 0 {
+		body["execute_errors"] = errs
+	}
+	writeJSON(w, body)
+}
+
+type executeCallArgs struct {
+	method string
+	params url.Values
+}
+
+func parseExecute(code string) ([]executeCallArgs, error) {
+	code = strings.TrimSpace(code)
+	if !strings.HasPrefix(code, "return [") || !strings.HasSuffix(code, "];") {
+		return nil, errors.New("vktest: unsupported execute code")
+	}
+	code = strings.TrimSuffix(strings.TrimPrefix(code, "return ["), "];")
+
+	var calls []executeCallArgs
+	for code != "" {
+		m := executeCall.FindStringSubmatch(code)
+		if m == nil {
+			return nil, errors.New("vktest: unsupported execute code")
+		}
+		code = code[len(m[0]):]
+
+		dec := json.NewDecoder(strings.NewReader(code))
+		var args map[string]string
+		if err := dec.Decode(&args); err != nil {
+			return nil, err
+		}
+		code = code[dec.InputOffset():]
+		if !strings.HasPrefix(code, ")") {
+			return nil, errors.New("vktest: unsupported execute code")
+		}
+		code = strings.TrimPrefix(strings.TrimPrefix(code, ")"), ",")
+
+		params := make(url.Values, len(args))
+		for k, v := range args {
+			params.Set(k, v)
+		}
+		calls = append(calls, executeCallArgs{method: m[1], params: params})
+	}
+	return calls, nil
+}
@@ -0,0 +1,178 @@
+package vktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LongPoll scripts the answers of a long poll server. Every a_check
+// request consumes the next queued step; without one the request is
+// held for up to Wait and then answered with no updates.
+type LongPoll struct {
+	// Wait caps the time an a_check request is held. The wait param
+	// sent by the client is honored if it is shorter.
+	Wait time.Duration
+
+	stringTS bool
+
+	mu       sync.Mutex
+	key      int
+	ts       int
+	steps    []lpStep
+	notify   chan struct{}
+	checks   int
+	requests []url.Values
+}
+
+type lpStep struct {
+	failed  int
+	updates []interface{}
+}
+
+func newLongPoll(stringTS bool) *LongPoll {
+	return &LongPoll{
+		Wait:     100 * time.Millisecond,
+		stringTS: stringTS,
+		key:      1,
+		ts:       1,
+		notify:   make(chan struct{}),
+	}
+}
+
+// Push queues a response delivering updates. Group updates are usually
+// vkapi.GroupLPUpdates values, user updates are []interface{} arrays.
+func (lp *LongPoll) Push(updates ...interface{}) {
+	lp.enqueue(lpStep{updates: updates})
+}
+
+// Fail queues a response with the given failed code:
+// 1 moves ts forward, 2 expires the key, 3 expires both key and ts.
+func (lp *LongPoll) Fail(code int) {
+	lp.enqueue(lpStep{failed: code})
+}
+
+// Checks returns the number of a_check requests served.
+func (lp *LongPoll) Checks() int {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	return lp.checks
+}
+
+// Requests returns the query params of every a_check request served,
+// e.g. to check the ts sent by the client.
+func (lp *LongPoll) Requests() []url.Values {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	return append([]url.Values(nil), lp.requests...)
+}
+
+func (lp *LongPoll) enqueue(s lpStep) {
+	lp.mu.Lock()
+	lp.steps = append(lp.steps, s)
+	close(lp.notify)
+	lp.notify = make(chan struct{})
+	lp.mu.Unlock()
+}
+
+// session returns the current key and ts for getLongPollServer.
+func (lp *LongPoll) session() (string, int) {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	return lp.keyString(), lp.ts
+}
+
+func (lp *LongPoll) keyString() string {
+	return "key" + strconv.Itoa(lp.key)
+}
+
+func (lp *LongPoll) serve(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	wait := lp.Wait
+	if sec, err := strconv.Atoi(q.Get("wait")); err == nil && time.Duration(sec)*time.Second < wait {
+		wait = time.Duration(sec) * time.Second
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		// A client that went away must not consume a step.
+		if r.Context().Err() != nil {
+			return
+		}
+
+		lp.mu.Lock()
+		if q.Get("key") != lp.keyString() {
+			lp.checks++
+			lp.requests = append(lp.requests, q)
+			lp.mu.Unlock()
+			writeJSON(w, map[string]int{"failed": 2})
+			return
+		}
+
+		if len(lp.steps) > 0 {
+			s := lp.steps[0]
+			lp.steps = lp.steps[1:]
+			lp.checks++
+			lp.requests = append(lp.requests, q)
+			resp := lp.apply(s)
+			lp.mu.Unlock()
+			writeJSON(w, resp)
+			return
+		}
+		notify := lp.notify
+		lp.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			lp.mu.Lock()
+			lp.checks++
+			lp.requests = append(lp.requests, q)
+			resp := map[string]interface{}{"ts": lp.tsValue(), "updates": []interface{}{}}
+			lp.mu.Unlock()
+			writeJSON(w, resp)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// apply must be called with lp.mu held.
+func (lp *LongPoll) apply(s lpStep) map[string]interface{} {
+	switch s.failed {
+	case 0:
+		lp.ts += len(s.updates)
+		updates := make([]json.RawMessage, 0, len(s.updates))
+		for _, u := range s.updates {
+			data, err := json.Marshal(u)
+			if err != nil {
+				panic(fmt.Sprintf("vktest: marshal update: %v", err))
+			}
+			updates = append(updates, data)
+		}
+		return map[string]interface{}{"ts": lp.tsValue(), "updates": updates}
+	case 1:
+		lp.ts += 10
+		return map[string]interface{}{"failed": 1, "ts": lp.tsValue()}
+	case 2:
+		lp.key++
+		return map[string]interface{}{"failed": 2}
+	default:
+		lp.key++
+		lp.ts += 100
+		return map[string]interface{}{"failed": s.failed}
+	}
+}
+
+func (lp *LongPoll) tsValue() interface{} {
+	if lp.stringTS {
+		return strconv.Itoa(lp.ts)
+	}
+	return lp.ts
+}
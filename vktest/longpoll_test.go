@@ -0,0 +1,102 @@
+package vktest_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+	"github.com/seilem/vk-golang-sdk/vktest"
+)
+
+func TestLongPoll(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     func(lp *vktest.LongPoll)
+		wantFailed int
+		wantTS     string
+		wantKey    bool // key still valid after the step
+		updates    int
+	}{
+		{"empty", func(*vktest.LongPoll) {}, 0, "1", true, 0},
+		{"push", func(lp *vktest.LongPoll) {
+			lp.Push(vkapi.GroupLPUpdates{Type: "message_new"}, vkapi.GroupLPUpdates{Type: "message_new"})
+		}, 0, "3", true, 2},
+		{"failed 1", func(lp *vktest.LongPoll) { lp.Fail(1) }, 1, "11", true, 0},
+		{"failed 2", func(lp *vktest.LongPoll) { lp.Fail(2) }, 2, "", false, 0},
+		{"failed 3", func(lp *vktest.LongPoll) { lp.Fail(3) }, 3, "", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := vktest.NewServer()
+			defer s.Close()
+			s.GroupLP.Wait = 10 * time.Millisecond
+			tt.script(s.GroupLP)
+
+			server, err := s.Client("token").GroupGetLPServer(&vkapi.GroupGetLPServerReq{GroupID: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp := check(t, server)
+			if resp.Failed != tt.wantFailed || resp.TS != tt.wantTS || len(resp.Updates) != tt.updates {
+				t.Errorf("a_check = %+v, want failed %d ts %q and %d updates", resp, tt.wantFailed, tt.wantTS, tt.updates)
+			}
+
+			if again := check(t, server); (again.Failed != 2) != tt.wantKey {
+				t.Errorf("second a_check failed = %d, want key valid %v", again.Failed, tt.wantKey)
+			}
+			if n := s.GroupLP.Checks(); n != 2 {
+				t.Errorf("Checks() = %d, want 2", n)
+			}
+			if reqs := s.GroupLP.Requests(); len(reqs) != 2 || reqs[0].Get("ts") != server.TS {
+				t.Errorf("Requests() = %v", reqs)
+			}
+		})
+	}
+}
+
+func TestLongPollWaitsForPush(t *testing.T) {
+	s := vktest.NewServer()
+	defer s.Close()
+	s.GroupLP.Wait = time.Second
+
+	server, err := s.Client("token").GroupGetLPServer(&vkapi.GroupGetLPServerReq{GroupID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(10*time.Millisecond, func() {
+		s.GroupLP.Push(vkapi.GroupLPUpdates{Type: "message_new"})
+	})
+	if resp := check(t, server); len(resp.Updates) != 1 {
+		t.Errorf("a_check = %+v, want the pushed update", resp)
+	}
+}
+
+type checkResp struct {
+	Failed  int               `json:"failed"`
+	TS      string            `json:"ts"`
+	Updates []json.RawMessage `json:"updates"`
+}
+
+func check(t *testing.T, server *vkapi.LPServer) checkResp {
+	t.Helper()
+	q := url.Values{"act": {"a_check"}, "key": {server.Key}, "ts": {server.TS}, "wait": {"1"}}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.Server+"?"+q.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var r checkResp
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
@@ -0,0 +1,294 @@
+// Package vktest provides an in-process fake of the VK API for tests.
+//
+//	srv := vktest.NewServer()
+//	defer srv.Close()
+//	srv.Handle("users.get", []vkapi.User{{ID: 1, FirstName: "Pavel"}})
+//	vk := srv.Client("token")
+package vktest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+)
+
+// HandlerFunc produces the response of a method from its params.
+// Returning a vkapi.APIError or a vkapi.ErrorCode makes the server
+// answer with an API error.
+type HandlerFunc func(params url.Values) (interface{}, error)
+
+// Request is an API call received by the server.
+type Request struct {
+	Method string
+	Values url.Values
+}
+
+// Upload is a file received by the upload endpoint.
+type Upload struct {
+	Field    string
+	Filename string
+	Data     []byte
+}
+
+// Server is a fake VK API server. Methods without a registered handler
+// answer with vkapi.ErrUnknownMethod, except long poll and upload
+// server methods which point to the server itself. Unless a handler is
+// registered for execute, the calls of code sent by vkapi.Batcher are
+// run against the other handlers and recorded as separate requests.
+type Server struct {
+	*httptest.Server
+
+	// GroupLP and UserLP script the Bots Long Poll and user Long Poll servers.
+	GroupLP *LongPoll
+	UserLP  *LongPoll
+
+	mu       sync.Mutex
+	handlers map[string]HandlerFunc
+	requests []Request
+	uploads  []Upload
+}
+
+// NewServer starts a fake server. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		GroupLP:  newLongPoll(true),
+		UserLP:   newLongPoll(false),
+		handlers: make(map[string]HandlerFunc),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/method/", s.serveMethod)
+	mux.HandleFunc("/lp/group", s.GroupLP.serve)
+	mux.HandleFunc("/lp/user", s.UserLP.serve)
+	mux.HandleFunc("/upload", s.serveUpload)
+	s.Server = httptest.NewServer(mux)
+
+	s.handleDefaults()
+	return s
+}
+
+// Client returns a client sending requests to s.
+func (s *Server) Client(token string) *vkapi.VkAPI {
+	vk := vkapi.NewVkAPIWithClient(token, s.Server.Client())
+	vk.BaseURL = s.URL + "/method/"
+	return vk
+}
+
+// UploadURL returns the URL accepting photo and video uploads.
+func (s *Server) UploadURL() string {
+	return s.URL + "/upload"
+}
+
+// Handle makes method answer with response encoded as JSON.
+func (s *Server) Handle(method string, response interface{}) {
+	s.HandleFunc(method, func(url.Values) (interface{}, error) {
+		return response, nil
+	})
+}
+
+// HandleError makes method answer with err, which should be
+// a vkapi.APIError or a vkapi.ErrorCode.
+func (s *Server) HandleError(method string, err error) {
+	s.HandleFunc(method, func(url.Values) (interface{}, error) {
+		return nil, err
+	})
+}
+
+// HandleFunc registers f as the handler of method.
+func (s *Server) HandleFunc(method string, f HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = f
+}
+
+// Requests returns params of every call of method in order of arrival.
+// An empty method returns all calls' params.
+func (s *Server) Requests(method string) []url.Values {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var r []url.Values
+	for _, req := range s.requests {
+		if method == "" || req.Method == method {
+			r = append(r, req.Values)
+		}
+	}
+	return r
+}
+
+// Calls returns every API call received by the server.
+func (s *Server) Calls() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Uploads returns files received by the upload endpoint.
+func (s *Server) Uploads() []Upload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Upload(nil), s.uploads...)
+}
+
+func (s *Server) handleDefaults() {
+	s.HandleFunc("groups.getLongPollServer", func(url.Values) (interface{}, error) {
+		key, ts := s.GroupLP.session()
+		return vkapi.LPServer{
+			Key:    key,
+			Server: s.URL + "/lp/group",
+			TS:     fmt.Sprint(ts),
+		}, nil
+	})
+	s.HandleFunc("messages.getLongPollServer", func(url.Values) (interface{}, error) {
+		key, ts := s.UserLP.session()
+		return vkapi.MsgLPServer{
+			Key:    key,
+			Server: strings.TrimPrefix(s.URL, "http://") + "/lp/user",
+			TS:     ts,
+		}, nil
+	})
+
+	upload := func(url.Values) (interface{}, error) {
+		return map[string]interface{}{"upload_url": s.UploadURL()}, nil
+	}
+	s.HandleFunc("photos.getWallUploadServer", upload)
+	s.HandleFunc("photos.getMessagesUploadServer", upload)
+	s.HandleFunc("video.save", func(params url.Values) (interface{}, error) {
+		return vkapi.VideoSaveResp{
+			Title:     params.Get("name"),
+			UploadURL: s.UploadURL(),
+			VideoID:   1,
+		}, nil
+	})
+}
+
+func (s *Server) serveMethod(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	method := strings.TrimPrefix(r.URL.Path, "/method/")
+
+	s.mu.Lock()
+	_, ok := s.handlers[method]
+	s.mu.Unlock()
+	if method == "execute" && !ok {
+		s.record(method, r.Form)
+		s.serveExecute(w, r.Form)
+		return
+	}
+
+	resp, err := s.call(method, r.Form)
+	if err != nil {
+		writeJSON(w, map[string]interface{}{"error": apiError(err, r.Form)})
+		return
+	}
+	writeJSON(w, map[string]interface{}{"response": resp})
+}
+
+func (s *Server) record(method string, params url.Values) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, Request{Method: method, Values: params})
+}
+
+// call records the call of method and runs its handler.
+func (s *Server) call(method string, params url.Values) (interface{}, error) {
+	s.record(method, params)
+
+	s.mu.Lock()
+	h, ok := s.handlers[method]
+	s.mu.Unlock()
+	if !ok {
+		return nil, vkapi.ErrUnknownMethod
+	}
+	return h(params)
+}
+
+func (s *Server) serveUpload(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var (
+		received []Upload
+		video    bool
+	)
+	for field, files := range r.MultipartForm.File {
+		for _, fh := range files {
+			f, err := fh.Open()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			data, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			received = append(received, Upload{Field: field, Filename: fh.Filename, Data: data})
+			if field == "video_file" {
+				video = true
+			}
+		}
+	}
+
+	s.mu.Lock()
+	s.uploads = append(s.uploads, received...)
+	s.mu.Unlock()
+
+	if video {
+		size := 0
+		for _, u := range received {
+			size += len(u.Data)
+		}
+		writeJSON(w, vkapi.UploadedVideoResp{Size: size, VideoID: 1})
+		return
+	}
+
+	photos := make([]map[string]string, 0, len(received))
+	for _, u := range received {
+		photos = append(photos, map[string]string{"name": u.Filename})
+	}
+	list, _ := json.Marshal(photos)
+	writeJSON(w, vkapi.UploadServer{Server: 1, Photo: string(list), Hash: "vktest"})
+}
+
+func apiError(err error, params url.Values) vkapi.APIError {
+	var e vkapi.APIError
+	if !errors.As(err, &e) {
+		var code vkapi.ErrorCode
+		if errors.As(err, &code) {
+			e = vkapi.APIError{Code: int(code), Msg: code.Error()}
+		} else {
+			e = vkapi.APIError{Code: int(vkapi.ErrUnknown), Msg: err.Error()}
+		}
+	}
+
+	if e.RequestParams == nil {
+		for k := range params {
+			if k == "access_token" {
+				continue
+			}
+			e.RequestParams = append(e.RequestParams, vkapi.RequestParam{Key: k, Value: params.Get(k)})
+		}
+	}
+	return e
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,224 @@
+package vktest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	vkapi "github.com/seilem/vk-golang-sdk"
+	"github.com/seilem/vk-golang-sdk/vktest"
+)
+
+func TestServerHandlers(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(s *vktest.Server)
+		want     string
+		wantErr  vkapi.ErrorCode
+	}{
+		{
+			name:     "unknown method",
+			register: func(*vktest.Server) {},
+			wantErr:  vkapi.ErrUnknownMethod,
+		},
+		{
+			name:     "handle",
+			register: func(s *vktest.Server) { s.Handle("users.get", []vkapi.User{{ID: 1}}) },
+			want:     `[{"id":1,"first_name":"","last_name":"","deactivated":"","is_closed":false,"can_access_closed":false}]`,
+		},
+		{
+			name:     "error code",
+			register: func(s *vktest.Server) { s.HandleError("users.get", vkapi.ErrAccessDenied) },
+			wantErr:  vkapi.ErrAccessDenied,
+		},
+		{
+			name: "api error",
+			register: func(s *vktest.Server) {
+				s.HandleError("users.get", vkapi.APIError{Code: int(vkapi.ErrCaptchaNeeded), CaptchaSID: "sid"})
+			},
+			wantErr: vkapi.ErrCaptchaNeeded,
+		},
+		{
+			name:     "plain error",
+			register: func(s *vktest.Server) { s.HandleError("users.get", errors.New("boom")) },
+			wantErr:  vkapi.ErrUnknown,
+		},
+		{
+			name: "handler func",
+			register: func(s *vktest.Server) {
+				s.HandleFunc("users.get", func(params url.Values) (interface{}, error) {
+					return params.Get("user_ids"), nil
+				})
+			},
+			want: `"1,2"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := vktest.NewServer()
+			defer s.Close()
+			tt.register(s)
+
+			resp, err := s.Client("token").MakeRequestContext(context.Background(), "users.get", url.Values{"user_ids": {"1,2"}})
+			if tt.wantErr != 0 {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				var e vkapi.APIError
+				if errors.As(err, &e) && len(e.RequestParams) == 0 {
+					t.Error("error has no request params")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(string(resp.Response)); got != tt.want {
+				t.Errorf("response = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerRequests(t *testing.T) {
+	s := vktest.NewServer()
+	defer s.Close()
+	s.Handle("users.get", []vkapi.User{})
+	s.Handle("groups.getById", []struct{}{})
+
+	vk := s.Client("token")
+	ctx := context.Background()
+	vk.MakeRequestContext(ctx, "users.get", url.Values{"user_ids": {"1"}})
+	vk.MakeRequestContext(ctx, "groups.getById", url.Values{"group_id": {"2"}})
+	vk.MakeRequestContext(ctx, "users.get", url.Values{"user_ids": {"3"}})
+
+	users := s.Requests("users.get")
+	if len(users) != 2 || users[0].Get("user_ids") != "1" || users[1].Get("user_ids") != "3" {
+		t.Errorf("Requests(users.get) = %v", users)
+	}
+	if all := s.Requests(""); len(all) != 3 {
+		t.Errorf("Requests(\"\") returned %d requests, want 3", len(all))
+	}
+
+	calls := s.Calls()
+	want := []string{"users.get", "groups.getById", "users.get"}
+	for i, c := range calls {
+		if c.Method != want[i] {
+			t.Errorf("Calls()[%d].Method = %q, want %q", i, c.Method, want[i])
+		}
+	}
+}
+
+func TestServerExecute(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       string
+		wantResp   string
+		wantErrors []string
+		wantErr    vkapi.ErrorCode
+	}{
+		{
+			name:     "calls",
+			code:     `return [API.users.get({"user_ids":"1"}),API.utils.echo({"text":"a,b"})];`,
+			wantResp: `[[{"id":1}],"a,b"]`,
+		},
+		{
+			name:       "failed call",
+			code:       `return [API.users.get({"user_ids":"1"}),API.wall.get({})];`,
+			wantResp:   `[[{"id":1}],false]`,
+			wantErrors: []string{"wall.get"},
+		},
+		{
+			name:    "unsupported code",
+			code:    `var a = API.users.get(); return a;`,
+			wantErr: vkapi.ErrInvalidParam,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := vktest.NewServer()
+			defer s.Close()
+			s.Handle("users.get", []map[string]int{{"id": 1}})
+			s.HandleFunc("utils.echo", func(params url.Values) (interface{}, error) {
+				return params.Get("text"), nil
+			})
+
+			resp, err := s.Client("token").MakeRequestContext(context.Background(), "execute", url.Values{"code": {tt.code}})
+			if tt.wantErr != 0 {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(resp.Response); got != tt.wantResp {
+				t.Errorf("response = %s, want %s", got, tt.wantResp)
+			}
+
+			var errs []vkapi.APIError
+			if len(resp.ExecuteErrors) > 0 {
+				if err := json.Unmarshal(resp.ExecuteErrors, &errs); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if len(errs) != len(tt.wantErrors) {
+				t.Fatalf("execute_errors = %s, want methods %v", resp.ExecuteErrors, tt.wantErrors)
+			}
+			for i, m := range tt.wantErrors {
+				if errs[i].Method != m || errs[i].Code != int(vkapi.ErrUnknownMethod) {
+					t.Errorf("execute_errors[%d] = %+v, want method %s", i, errs[i], m)
+				}
+			}
+			if n := len(s.Requests("users.get")); n != 1 {
+				t.Errorf("users.get recorded %d times, want 1", n)
+			}
+		})
+	}
+}
+
+func TestServerUploads(t *testing.T) {
+	s := vktest.NewServer()
+	defer s.Close()
+	vk := s.Client("token")
+
+	server, err := vk.GetWallUploadServerContext(context.Background(), &vkapi.GetWallUploadServerReq{GroupID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := vkapi.MakeUploadPhotoRequest(server.UploadURL, []vkapi.File{
+		{Name: "a.jpg", Data: strings.NewReader("aaa")},
+		{Name: "b.jpg", Data: strings.NewReader("bb")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var uploaded vkapi.UploadServer
+	if err := json.NewDecoder(resp.Body).Decode(&uploaded); err != nil {
+		t.Fatal(err)
+	}
+	if uploaded.Hash == "" || !strings.Contains(uploaded.Photo, "a.jpg") {
+		t.Errorf("upload response = %+v", uploaded)
+	}
+
+	uploads := s.Uploads()
+	if len(uploads) != 2 {
+		t.Fatalf("Uploads() = %+v, want 2 files", uploads)
+	}
+	for _, u := range uploads {
+		if u.Filename == "a.jpg" && string(u.Data) != "aaa" || u.Filename == "b.jpg" && string(u.Data) != "bb" {
+			t.Errorf("upload %s has data %q", u.Filename, u.Data)
+		}
+	}
+}
@@ -1,29 +1,27 @@
 package vkapi
 
 import (
-	"encoding/json"
+	"context"
 	"net/url"
-	"strconv"
-	"strings"
 )
 
 type WallGetReq struct {
-	OwnerID  int
-	Domain   string
-	Offset   int
-	Count    int
-	Filter   string
-	Extended bool
-	Fields   []string
+	OwnerID  int      `vk:"owner_id,omitempty"`
+	Domain   string   `vk:"domain,omitempty"`
+	Offset   int      `vk:"offset,omitempty"`
+	Count    int      `vk:"count,omitempty"`
+	Filter   string   `vk:"filter,omitempty"`
+	Extended bool     `vk:"extended,omitempty"`
+	Fields   []string `vk:"fields,omitempty"`
 }
 
 type WallPostReq struct {
-	OwnerID     int
-	FriendOnly  bool
-	FromGroup   bool
-	Message     string
-	Copyright   string
-	Attachments []string
+	OwnerID     int      `vk:"owner_id,omitempty"`
+	FriendOnly  bool     `vk:"friends_only,omitempty"`
+	FromGroup   bool     `vk:"from_group,omitempty"`
+	Message     string   `vk:"message,omitempty"`
+	Copyright   string   `vk:"copyright,omitempty"`
+	Attachments []string `vk:"attachments,omitempty"`
 }
 
 type WallPostResp struct {
@@ -34,80 +32,68 @@ func (w WallPostReq) Name() string {
 	return "wall.post"
 }
 
-func (w WallPostReq) Values() url.Values {
-	v := url.Values{}
-	if w.OwnerID != 0 {
-		v.Set("owner_id", strconv.Itoa(w.OwnerID))
-	}
-	v.Set("friend_only", strconv.Itoa(btoi(w.FriendOnly)))
-	v.Set("from_group", strconv.Itoa(btoi(w.FromGroup)))
-	if len(w.Message) > 0 {
-		v.Set("message", w.Message)
-	}
+// EncodeValues returns the params of w.
+func (w WallPostReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(w)
+}
 
-	if len(w.Copyright) > 0 {
-		v.Set("copyright", w.Copyright)
-	}
+// Values returns the params of w.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
+func (w WallPostReq) Values() url.Values {
+	values, _ := EncodeValues(w)
+	return values
+}
 
-	if len(w.Attachments) > 0 {
-		v.Set("attachments", strings.Join(w.Attachments, ","))
-	}
-	return v
+func (w WallPostReq) Validate() error {
+	return validate(w.Name(),
+		requiredOneOf("message, attachments", w.Message != "" || len(w.Attachments) > 0),
+		maxItems("attachments", len(w.Attachments), MaxAttachments),
+	)
 }
 
 func (WallGetReq) Name() string {
 	return "wall.get"
 }
 
-func (w *WallGetReq) Values() url.Values {
-	v := url.Values{}
-	if w.OwnerID != 0 {
-		v.Set("owner_id", strconv.Itoa(w.OwnerID))
-	}
-
-	if len(w.Domain) > 0 {
-		v.Set("domain", w.Domain)
-	}
-
-	v.Set("offset", strconv.Itoa(w.Offset))
-
-	if w.Count != 0 {
-		v.Set("count", strconv.Itoa(w.Count))
-	}
-
-	if len(w.Filter) > 0 {
-		v.Set("filter", w.Filter)
-	}
-
-	v.Set("extended", strconv.Itoa(btoi(w.Extended)))
+// EncodeValues returns the params of w.
+func (w *WallGetReq) EncodeValues() (url.Values, error) {
+	return EncodeValues(w)
+}
 
-	if len(w.Fields) > 0 {
-		v.Set("fields", strings.Join(w.Fields, ","))
-	}
+// Values returns the params of w.
+//
+// Deprecated: use EncodeValues, which also reports encoding errors.
+func (w *WallGetReq) Values() url.Values {
+	values, _ := EncodeValues(w)
+	return values
+}
 
-	return v
+func (w WallGetReq) Validate() error {
+	return validate(w.Name(),
+		inRange("count", w.Count, 0, 100),
+		rule{ok: w.Offset >= 0, param: "offset", reason: "must not be negative"},
+	)
 }
 
 func (vk *VkAPI) WallGet(r *WallGetReq) (*Wall, error) {
-	resp, err := vk.MakeRequest(r.Name(), r.Values())
-	if err != nil {
-		return nil, err
-	}
-	var w Wall
-	if err := json.Unmarshal(resp.Response, &w); err != nil {
-		return nil, err
-	}
-	return &w, nil
+	return vk.WallGetContext(context.Background(), r)
+}
+
+// WallGetContext is like WallGet but uses ctx for the request.
+func (vk *VkAPI) WallGetContext(ctx context.Context, r *WallGetReq) (*Wall, error) {
+	return Call[*Wall](ctx, vk, r)
 }
 
 func (vk *VkAPI) WallPost(r *WallPostReq) (int64, error) {
-	resp, err := vk.MakeRequest(r.Name(), r.Values())
+	return vk.WallPostContext(context.Background(), r)
+}
+
+// WallPostContext is like WallPost but uses ctx for the request.
+func (vk *VkAPI) WallPostContext(ctx context.Context, r *WallPostReq) (int64, error) {
+	w, err := Call[WallPostResp](ctx, vk, r)
 	if err != nil {
 		return 0, err
 	}
-	var w WallPostResp
-	if err := json.Unmarshal(resp.Response, &w); err != nil {
-		return 0, err
-	}
 	return w.PostID, nil
 }